@@ -0,0 +1,237 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var (
+	errTokenRevoked   = errors.New("token revocado")
+	errRefreshInvalid = errors.New("refresh token inválido")
+	errRefreshReused  = errors.New("refresh token reutilizado")
+)
+
+// RefreshToken se guarda solo como hash. Cada uso lo gasta y emite otro de la misma
+// familia (FamilyID, una por login; ver UserSession); si llega uno ya gastado alguien lo copió y se
+// revoca la familia entera. AccessJTI es el access token emitido junto a él, para
+// poder invalidarlo también.
+type RefreshToken struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	UserID          uint       `gorm:"not null;index" json:"user_id"`
+	FamilyID        string     `gorm:"size:64;not null;index" json:"family_id"`
+	TokenHash       string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	AccessJTI       string     `gorm:"size:64;index" json:"-"`
+	AccessExpiresAt time.Time  `json:"access_expires_at"`
+	ExpiresAt       time.Time  `gorm:"not null;index" json:"expires_at"`
+	UsedAt          *time.Time `json:"used_at"`
+	RevokedAt       *time.Time `json:"revoked_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// RevokedToken es la lista de access tokens invalidados antes de expirar (por jti).
+// Las filas sobran en cuanto el token habría expirado igualmente.
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey;size:64" json:"jti"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// tokenRevoked se consulta en cada petición autenticada; va contra la base y no contra
+// memoria para que un logout valga en todas las instancias.
+func tokenRevoked(db *gorm.DB, jti string) bool {
+	var n int64
+	db.Model(&RevokedToken{}).Where("jti = ?", jti).Count(&n)
+	return n > 0
+}
+
+func revokeAccessToken(tx *gorm.DB, jti string, expiresAt time.Time) error {
+	if jti == "" || !expiresAt.After(time.Now()) {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&RevokedToken{JTI: jti, ExpiresAt: expiresAt}).Error
+}
+
+// revokeFamily invalida todos los refresh tokens de la familia y sus access tokens vigentes
+func revokeFamily(tx *gorm.DB, familyID string) error {
+	now := time.Now()
+	var live []RefreshToken
+	if err := tx.Where("family_id = ? AND access_expires_at > ?", familyID, now).Find(&live).Error; err != nil {
+		return err
+	}
+	for _, rt := range live {
+		if err := revokeAccessToken(tx, rt.AccessJTI, rt.AccessExpiresAt); err != nil {
+			return err
+		}
+	}
+	if err := tx.Model(&RefreshToken{}).Where("family_id = ? AND revoked_at IS NULL", familyID).Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return tx.Model(&UserSession{}).Where("family_id = ? AND revoked_at IS NULL", familyID).Update("revoked_at", now).Error
+}
+
+// issueTokens emite access + refresh dentro de la familia (sesión) familyID
+func issueTokens(tx *gorm.DB, secret string, u *User, familyID string) (*AuthResponse, *RefreshToken, error) {
+	access, claims, err := makeToken(secret, u, familyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	refresh, err := randomToken(32)
+	if err != nil {
+		return nil, nil, err
+	}
+	rt := &RefreshToken{UserID: u.ID, FamilyID: familyID, TokenHash: hashToken(refresh), AccessJTI: claims.ID,
+		AccessExpiresAt: claims.ExpiresAt.Time, ExpiresAt: time.Now().Add(refreshTokenTTL)}
+	if err := tx.Create(rt).Error; err != nil {
+		return nil, nil, err
+	}
+	return &AuthResponse{Token: access, RefreshToken: refresh, ExpiresIn: int(accessTokenTTL.Seconds())}, rt, nil
+}
+
+// rotateRefreshToken gasta el refresh token y emite el siguiente de la familia
+func rotateRefreshToken(db *gorm.DB, secret, refresh, ip string) (*AuthResponse, error) {
+	var resp *AuthResponse
+	var reused *RefreshToken
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var rt RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", hashToken(refresh)).First(&rt).Error; err != nil {
+			return errRefreshInvalid
+		}
+		now := time.Now()
+		if rt.UsedAt != nil {
+			reused = &rt
+			return errRefreshReused
+		}
+		if rt.RevokedAt != nil || !rt.ExpiresAt.After(now) {
+			return errRefreshInvalid
+		}
+		var u User
+		if err := tx.First(&u, rt.UserID).Error; err != nil {
+			return errRefreshInvalid
+		}
+		if err := tx.Model(&rt).Update("used_at", now).Error; err != nil {
+			return err
+		}
+		var next *RefreshToken
+		var err error
+		if resp, next, err = issueTokens(tx, secret, &u, rt.FamilyID); err != nil {
+			return err
+		}
+		return touchSession(tx, rt.FamilyID, ip, next.ExpiresAt)
+	})
+	// la revocación va fuera de la transacción, que se deshace al devolver error
+	if reused != nil {
+		if err := db.Transaction(func(tx *gorm.DB) error { return revokeFamily(tx, reused.FamilyID) }); err != nil {
+			log.Printf("no se pudo revocar la familia de tokens del usuario %d: %v", reused.UserID, err)
+		}
+	}
+	return resp, err
+}
+
+// purgeExpiredTokens borra periódicamente denylist, refresh tokens, sesiones y enlaces de
+// desbloqueo que ya no sirven
+func purgeExpiredTokens(db *gorm.DB, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for range t.C {
+		now := time.Now()
+		if err := db.Where("expires_at <= ?", now).Delete(&RevokedToken{}).Error; err != nil {
+			log.Printf("no se pudieron purgar tokens revocados: %v", err)
+		}
+		if err := db.Where("expires_at <= ?", now).Delete(&RefreshToken{}).Error; err != nil {
+			log.Printf("no se pudieron purgar refresh tokens: %v", err)
+		}
+		if err := db.Where("expires_at <= ?", now).Delete(&UserSession{}).Error; err != nil {
+			log.Printf("no se pudieron purgar sesiones: %v", err)
+		}
+		if err := db.Where("expires_at <= ?", now).Delete(&UnlockToken{}).Error; err != nil {
+			log.Printf("no se pudieron purgar enlaces de desbloqueo: %v", err)
+		}
+	}
+}
+
+func registerAuthTokenRoutes(api fiber.Router, db *gorm.DB, jwtSecret string) {
+	api.Post("/auth/refresh", func(c *fiber.Ctx) error {
+		var body struct {
+			RefreshToken string `json:"refreshToken"`
+		}
+		if err := c.BodyParser(&body); err != nil || body.RefreshToken == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payload inválido"})
+		}
+		resp, err := rotateRefreshToken(db, jwtSecret, body.RefreshToken, c.IP())
+		if errors.Is(err, errRefreshInvalid) || errors.Is(err, errRefreshReused) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo emitir token"})
+		}
+		return c.JSON(resp)
+	})
+
+	// cierra la sesión del access token y/o del refresh token recibidos
+	api.Post("/auth/logout", func(c *fiber.Ctx) error {
+		claims, _ := optionalAuth(c, db, jwtSecret)
+		var body struct {
+			RefreshToken string `json:"refreshToken"`
+		}
+		_ = c.BodyParser(&body)
+		if claims == nil && body.RefreshToken == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "no autorizado"})
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			families := map[string]bool{}
+			if claims != nil {
+				if err := revokeAccessToken(tx, claims.ID, claims.ExpiresAt.Time); err != nil {
+					return err
+				}
+				var rt RefreshToken
+				if claims.ID != "" && tx.Where("access_jti = ?", claims.ID).First(&rt).Error == nil {
+					families[rt.FamilyID] = true
+				}
+			}
+			if body.RefreshToken != "" {
+				var rt RefreshToken
+				if tx.Where("token_hash = ?", hashToken(body.RefreshToken)).First(&rt).Error == nil &&
+					(claims == nil || rt.UserID == claims.UserID) {
+					families[rt.FamilyID] = true
+				}
+			}
+			for f := range families {
+				if err := revokeFamily(tx, f); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo cerrar la sesión"})
+		}
+		return c.JSON(fiber.Map{"ok": true})
+	})
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB abre la base de TEST_DATABASE_URL; sin ella los tests que la necesitan se saltan.
+// Usa una base desechable: migra las tablas que toca el test.
+func testDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL no está definida")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("no se pudo conectar a la base de test: %v", err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migración: %v", err)
+	}
+	return db
+}
+
+// testUser crea un usuario con datos únicos y lo borra (con sus tokens y sesiones) al terminar
+func testUser(t *testing.T, db *gorm.DB) *User {
+	t.Helper()
+	tag, err := randomToken(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := &User{Username: "test-" + tag, Email: "test-" + tag + "@example.com", PasswordHash: "x"}
+	if err := db.Create(u).Error; err != nil {
+		t.Fatalf("crear usuario: %v", err)
+	}
+	t.Cleanup(func() {
+		var jtis []string
+		db.Model(&RefreshToken{}).Where("user_id = ?", u.ID).Pluck("access_jti", &jtis)
+		db.Where("jti IN ?", append(jtis, "")).Delete(&RevokedToken{})
+		db.Where("user_id = ?", u.ID).Delete(&RefreshToken{})
+		db.Where("user_id = ?", u.ID).Delete(&UserSession{})
+		db.Delete(u)
+	})
+	return u
+}
+
+// testSession hace lo mismo que startSession sin necesitar una petición
+func testSession(t *testing.T, db *gorm.DB, secret string, u *User) (*AuthResponse, string) {
+	t.Helper()
+	familyID, err := randomToken(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, rt, err := issueTokens(db, secret, u, familyID)
+	if err != nil {
+		t.Fatalf("issueTokens: %v", err)
+	}
+	s := &UserSession{UserID: u.ID, FamilyID: familyID, LastSeenAt: time.Now(), ExpiresAt: rt.ExpiresAt}
+	if err := db.Create(s).Error; err != nil {
+		t.Fatalf("crear sesión: %v", err)
+	}
+	return resp, familyID
+}
+
+func TestRotateRefreshTokenReuseRevokesFamily(t *testing.T) {
+	db := testDB(t, &User{}, &RefreshToken{}, &RevokedToken{}, &UserSession{})
+	const secret = "test-secret"
+	u := testUser(t, db)
+	first, family := testSession(t, db, secret, u)
+	other, otherFamily := testSession(t, db, secret, u)
+
+	second, err := rotateRefreshToken(db, secret, first.RefreshToken, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("primer refresh: %v", err)
+	}
+	third, err := rotateRefreshToken(db, secret, second.RefreshToken, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("segundo refresh: %v", err)
+	}
+
+	// alguien usa la copia del primer refresh token
+	if _, err := rotateRefreshToken(db, secret, first.RefreshToken, "10.0.0.2"); !errors.Is(err, errRefreshReused) {
+		t.Fatalf("reutilizar un refresh gastado = %v; se esperaba errRefreshReused", err)
+	}
+
+	// el último refresh de la familia deja de valer, igual que su access token
+	if _, err := rotateRefreshToken(db, secret, third.RefreshToken, "10.0.0.1"); !errors.Is(err, errRefreshInvalid) {
+		t.Fatalf("refresh tras la reutilización = %v; se esperaba errRefreshInvalid", err)
+	}
+	for i, access := range []string{first.Token, second.Token, third.Token} {
+		if _, err := parseToken(db, secret, access); !errors.Is(err, errTokenRevoked) {
+			t.Errorf("access token %d de la familia = %v; se esperaba errTokenRevoked", i+1, err)
+		}
+	}
+	var live int64
+	db.Model(&RefreshToken{}).Where("family_id = ? AND revoked_at IS NULL", family).Count(&live)
+	if live != 0 {
+		t.Errorf("quedan %d refresh tokens sin revocar en la familia", live)
+	}
+	var s UserSession
+	if err := db.Where("family_id = ?", family).First(&s).Error; err != nil || s.RevokedAt == nil {
+		t.Errorf("la sesión de la familia no quedó revocada (err=%v)", err)
+	}
+
+	// las demás sesiones del usuario no se tocan
+	if _, err := parseToken(db, secret, other.Token); err != nil {
+		t.Errorf("access token de otra sesión: %v", err)
+	}
+	if _, err := rotateRefreshToken(db, secret, other.RefreshToken, "10.0.0.3"); err != nil {
+		t.Errorf("refresh de otra sesión: %v", err)
+	}
+	if err := db.Where("family_id = ?", otherFamily).First(&s).Error; err != nil || s.RevokedAt != nil {
+		t.Errorf("la otra sesión quedó revocada (err=%v)", err)
+	}
+}
+
+func TestRotateRefreshTokenInvalid(t *testing.T) {
+	db := testDB(t, &User{}, &RefreshToken{}, &RevokedToken{}, &UserSession{})
+	const secret = "test-secret"
+	u := testUser(t, db)
+	resp, family := testSession(t, db, secret, u)
+
+	if _, err := rotateRefreshToken(db, secret, "no-existe", "10.0.0.1"); !errors.Is(err, errRefreshInvalid) {
+		t.Fatalf("token desconocido = %v; se esperaba errRefreshInvalid", err)
+	}
+	db.Model(&RefreshToken{}).Where("family_id = ?", family).Update("expires_at", time.Now().Add(-time.Minute))
+	if _, err := rotateRefreshToken(db, secret, resp.RefreshToken, "10.0.0.1"); !errors.Is(err, errRefreshInvalid) {
+		t.Fatalf("token vencido = %v; se esperaba errRefreshInvalid", err)
+	}
+}
@@ -0,0 +1,462 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// roles de colaboración, igual que el CHECK de collaborations en db.sql
+const (
+	roleOwner       = "owner"
+	roleEditor      = "editor"
+	roleViewer      = "viewer"
+	roleContributor = "contributor"
+)
+
+const invitationTTL = 7 * 24 * time.Hour
+
+type Collaboration struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	RoadmapID      uint      `gorm:"not null;uniqueIndex:idx_collab_roadmap_user" json:"roadmap_id"`
+	CollaboratorID uint      `gorm:"index;not null;uniqueIndex:idx_collab_roadmap_user" json:"collaborator_id"`
+	Role           string    `gorm:"size:16;not null;default:viewer" json:"role"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type RoadmapInvitation struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	RoadmapID  uint       `gorm:"index;not null" json:"roadmap_id"`
+	Email      string     `gorm:"size:255;not null" json:"email"`
+	Role       string     `gorm:"size:16;not null" json:"role"`
+	InvitedBy  uint       `gorm:"not null" json:"invited_by"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type invitationClaims struct {
+	RoadmapID uint   `json:"rid"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// normalizeRole acepta también los nombres que usa el editor (collaborator, reader)
+func normalizeRole(v string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case roleOwner:
+		return roleOwner, true
+	case roleEditor:
+		return roleEditor, true
+	case roleViewer, "reader":
+		return roleViewer, true
+	case roleContributor, "collaborator":
+		return roleContributor, true
+	}
+	return "", false
+}
+
+// roadmapRole devuelve el rol del usuario en el roadmap o "" si no tiene ninguno.
+// Si hay fila en collaborations manda su rol; si no, la fila de UserRoadmap del autor
+// (roadmaps anteriores a collaborations) cuenta como dueño. ownersQuery, scopeOwnedBy y
+// scopeSharedWith siguen la misma regla.
+func roadmapRole(db *gorm.DB, userID, roadmapID uint) string {
+	var cb Collaboration
+	if err := db.Where("collaborator_id = ? AND roadmap_id = ?", userID, roadmapID).First(&cb).Error; err == nil {
+		return cb.Role
+	}
+	var ur UserRoadmap
+	if err := db.Where("user_id = ? AND roadmap_id = ?", userID, roadmapID).First(&ur).Error; err == nil {
+		return roleOwner
+	}
+	return ""
+}
+
+// la clave de invitaciones se deriva del secreto para que no sirvan como access token
+func invitationKey(secret string) []byte {
+	return []byte("invitation:" + secret)
+}
+
+func makeInvitationToken(secret string, inv *RoadmapInvitation) (string, error) {
+	claims := invitationClaims{
+		RoadmapID: inv.RoadmapID,
+		Email:     inv.Email,
+		Role:      inv.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        strconv.FormatUint(uint64(inv.ID), 10),
+			ExpiresAt: jwt.NewNumericDate(inv.ExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(inv.CreatedAt),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(invitationKey(secret))
+}
+
+func parseInvitationToken(secret, token string) (*invitationClaims, error) {
+	parsed, err := jwt.ParseWithClaims(token, &invitationClaims{}, func(t *jwt.Token) (interface{}, error) {
+		return invitationKey(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	if claims, ok := parsed.Claims.(*invitationClaims); ok && parsed.Valid {
+		return claims, nil
+	}
+	return nil, fmt.Errorf("invalid invitation")
+}
+
+func registerCollaboratorRoutes(api fiber.Router, db *gorm.DB, jwtSecret string) {
+	api.Get("/learning-paths/:id/collaborators", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var r Roadmap
+		if err := db.First(&r, id).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		myRole := roadmapRole(db, claims.UserID, r.ID)
+		if !roleCan(myRole, actionRead) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden"})
+		}
+		var collabs []Collaboration
+		if err := db.Where("roadmap_id = ?", r.ID).Order("created_at asc").Find(&collabs).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"items": []fiber.Map{}})
+		}
+		roles := map[uint]string{}
+		userIDs := make([]uint, 0, len(collabs)+1)
+		for _, cb := range collabs {
+			roles[cb.CollaboratorID] = cb.Role
+			userIDs = append(userIDs, cb.CollaboratorID)
+		}
+		// autores de roadmaps antiguos sin fila en collaborations
+		var owners []UserRoadmap
+		db.Where("roadmap_id = ?", r.ID).Find(&owners)
+		for _, ur := range owners {
+			if _, ok := roles[ur.UserID]; !ok {
+				roles[ur.UserID] = roleOwner
+				userIDs = append(userIDs, ur.UserID)
+			}
+		}
+		var users []User
+		if len(userIDs) > 0 {
+			if err := db.Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+				users = []User{}
+			}
+		}
+		uname := map[uint]string{}
+		for _, u := range users {
+			uname[u.ID] = u.Username
+		}
+		items := make([]fiber.Map, 0, len(userIDs))
+		for _, id := range userIDs {
+			items = append(items, fiber.Map{"userId": id, "username": uname[id], "role": roles[id]})
+		}
+		out := fiber.Map{"items": items}
+		if roleCan(myRole, actionManage) {
+			var invs []RoadmapInvitation
+			db.Where("roadmap_id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", r.ID, time.Now()).Order("created_at desc").Find(&invs)
+			pending := make([]fiber.Map, 0, len(invs))
+			for _, inv := range invs {
+				pending = append(pending, fiber.Map{"id": inv.ID, "email": inv.Email, "role": inv.Role, "expiresAt": inv.ExpiresAt})
+			}
+			out["invitations"] = pending
+		}
+		return c.JSON(out)
+	})
+
+	api.Post("/learning-paths/:id/invite", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		var body struct {
+			Email string `json:"email"`
+			Role  string `json:"role"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payload inválido"})
+		}
+		email := normalizeEmail(body.Email)
+		if email == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "faltan campos"})
+		}
+		role := roleContributor
+		if body.Role != "" {
+			var ok bool
+			if role, ok = normalizeRole(body.Role); !ok {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "rol inválido"})
+			}
+		}
+		// la propiedad solo se cede a quien ya colabora (PUT .../collaborators/:userId)
+		if role == roleOwner {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "no se puede invitar como dueño; cambia el rol cuando acepte"})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var r Roadmap
+		if err := db.First(&r, id).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		if !canAccess(db, claims.UserID, &r, actionManage) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden"})
+		}
+		var invitee User
+		hasAccount := db.Where("lower(email) = ?", email).First(&invitee).Error == nil
+		if hasAccount && roadmapRole(db, invitee.ID, r.ID) == roleOwner {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "ese usuario ya es dueño del roadmap"})
+		}
+		now := time.Now()
+		inv := &RoadmapInvitation{RoadmapID: r.ID, Email: email, Role: role, InvitedBy: claims.UserID, ExpiresAt: now.Add(invitationTTL), CreatedAt: now}
+		if err := db.Create(inv).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo invitar"})
+		}
+		token, err := makeInvitationToken(jwtSecret, inv)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo emitir token"})
+		}
+		// si el invitado ya tiene cuenta se entera sin esperar al correo
+		if hasAccount {
+			notify(db, []uint{invitee.ID}, claims.UserID, notifyInvite, r.ID,
+				fmt.Sprintf("%s te invitó a colaborar en «%s» como %s", usernamesByID(db, []uint{claims.UserID})[claims.UserID], r.Title, inv.Role))
+		}
+		return c.JSON(fiber.Map{"token": token, "role": inv.Role, "expiresAt": inv.ExpiresAt})
+	})
+
+	api.Post("/learning-paths/invitations/:token/accept", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		ic, err := parseInvitationToken(jwtSecret, c.Params("token"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invitación inválida o expirada"})
+		}
+		// el correo del access token puede estar desactualizado: se compara con el de la base,
+		// y solo cuenta si el usuario demostró que es suyo
+		var u User
+		if err := db.First(&u, claims.UserID).Error; err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": errUnauthorized.Error()})
+		}
+		if normalizeEmail(ic.Email) != normalizeEmail(u.Email) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "la invitación es para otro usuario"})
+		}
+		if !emailVerified(db, u.ID) {
+			return denyUnverified(c)
+		}
+		var inv RoadmapInvitation
+		if err := db.First(&inv, ic.ID).Error; err != nil || inv.RoadmapID != ic.RoadmapID {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "invitación no encontrada"})
+		}
+		if inv.RevokedAt != nil || inv.AcceptedAt != nil {
+			return c.Status(fiber.StatusGone).JSON(fiber.Map{"error": "invitación ya usada"})
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			now := time.Now()
+			res := tx.Model(&RoadmapInvitation{}).Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", inv.ID).Update("accepted_at", now)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return errInvitationUsed
+			}
+			// si ya colabora solo cambiamos el rol; un dueño (también el autor que solo
+			// tiene la fila de UserRoadmap) se queda como está
+			switch roadmapRole(tx, claims.UserID, inv.RoadmapID) {
+			case roleOwner:
+				return nil
+			case "":
+				return tx.Create(&Collaboration{RoadmapID: inv.RoadmapID, CollaboratorID: claims.UserID, Role: inv.Role}).Error
+			default:
+				return tx.Model(&Collaboration{}).Where("collaborator_id = ? AND roadmap_id = ?", claims.UserID, inv.RoadmapID).Update("role", inv.Role).Error
+			}
+		})
+		if errors.Is(err, errInvitationUsed) {
+			return c.Status(fiber.StatusGone).JSON(fiber.Map{"error": "invitación ya usada"})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo aceptar"})
+		}
+		return c.JSON(fiber.Map{"ok": true, "roadmapId": inv.RoadmapID, "role": roadmapRole(db, claims.UserID, inv.RoadmapID)})
+	})
+
+	api.Delete("/learning-paths/:id/invitations/:invitationId", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var r Roadmap
+		if err := db.First(&r, id).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		if !canAccess(db, claims.UserID, &r, actionManage) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden"})
+		}
+		invitationID, err := paramID(c, "invitationId")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		res := db.Model(&RoadmapInvitation{}).Where("id = ? AND roadmap_id = ? AND accepted_at IS NULL AND revoked_at IS NULL", invitationID, r.ID).Update("revoked_at", time.Now())
+		if res.Error != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"ok": false})
+		}
+		if res.RowsAffected == 0 {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "invitación no encontrada"})
+		}
+		return c.JSON(fiber.Map{"ok": true})
+	})
+
+	api.Put("/learning-paths/:id/collaborators/:userId", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		var body struct {
+			Role string `json:"role"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payload inválido"})
+		}
+		role, ok := normalizeRole(body.Role)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "rol inválido"})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var r Roadmap
+		if err := db.First(&r, id).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		if !canAccess(db, claims.UserID, &r, actionManage) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden"})
+		}
+		userID, err := paramID(c, "userId")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "usuario inválido"})
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			current, err := lockOwnership(tx, r.ID, userID)
+			if err != nil {
+				return err
+			}
+			if current == roleOwner && role != roleOwner && countOwners(tx, r.ID) <= 1 {
+				return errLastOwner
+			}
+			if err := tx.Where("collaborator_id = ? AND roadmap_id = ?", userID, r.ID).Delete(&Collaboration{}).Error; err != nil {
+				return err
+			}
+			// UserRoadmap se queda: registra quién es el autor; los permisos los decide la colaboración
+			return tx.Create(&Collaboration{RoadmapID: r.ID, CollaboratorID: userID, Role: role}).Error
+		})
+		if err != nil {
+			return collaboratorError(c, err, "no se pudo actualizar")
+		}
+		return c.JSON(fiber.Map{"userId": userID, "role": role})
+	})
+
+	api.Delete("/learning-paths/:id/collaborators/:userId", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var r Roadmap
+		if err := db.First(&r, id).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		userID, err := paramID(c, "userId")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "usuario inválido"})
+		}
+		// el dueño revoca a cualquiera; un colaborador puede salirse por su cuenta
+		if userID != claims.UserID && !canAccess(db, claims.UserID, &r, actionManage) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden"})
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			current, err := lockOwnership(tx, r.ID, userID)
+			if err != nil {
+				return err
+			}
+			if current == roleOwner && countOwners(tx, r.ID) <= 1 {
+				return errLastOwner
+			}
+			if err := tx.Where("collaborator_id = ? AND roadmap_id = ?", userID, r.ID).Delete(&Collaboration{}).Error; err != nil {
+				return err
+			}
+			return tx.Where("user_id = ? AND roadmap_id = ?", userID, r.ID).Delete(&UserRoadmap{}).Error
+		})
+		if err != nil {
+			return collaboratorError(c, err, "no se pudo quitar")
+		}
+		return c.JSON(fiber.Map{"ok": true})
+	})
+}
+
+var (
+	errInvitationUsed  = errors.New("invitation already used")
+	errNotCollaborator = errors.New("colaborador no encontrado")
+	errLastOwner       = errors.New("el roadmap debe tener al menos un dueño")
+)
+
+// lockOwnership bloquea el roadmap hasta el final de la transacción y devuelve el rol
+// actual de userID. Así dos cambios de rol simultáneos no pasan a la vez la comprobación
+// de countOwners y dejan el roadmap sin dueño.
+func lockOwnership(tx *gorm.DB, roadmapID, userID uint) (string, error) {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&Roadmap{}, roadmapID).Error; err != nil {
+		return "", err
+	}
+	current := roadmapRole(tx, userID, roadmapID)
+	if current == "" {
+		return "", errNotCollaborator
+	}
+	return current, nil
+}
+
+func collaboratorError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, errNotCollaborator):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errLastOwner):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+}
+
+// ownersQuery selecciona los ids de los dueños del roadmap con la regla de roadmapRole
+func ownersQuery(db *gorm.DB, roadmapID uint) *gorm.DB {
+	return db.Raw(`SELECT collaborator_id AS uid FROM collaborations WHERE roadmap_id = ? AND role = ?
+		UNION
+		SELECT ur.user_id FROM user_roadmaps ur WHERE ur.roadmap_id = ?
+			AND NOT EXISTS (SELECT 1 FROM collaborations cb WHERE cb.roadmap_id = ur.roadmap_id AND cb.collaborator_id = ur.user_id)`,
+		roadmapID, roleOwner, roadmapID)
+}
+
+func countOwners(db *gorm.DB, roadmapID uint) int64 {
+	var n int64
+	db.Raw("SELECT COUNT(*) FROM (?) owners", ownersQuery(db, roadmapID)).Scan(&n)
+	return n
+}
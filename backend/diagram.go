@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// El editor guarda lo que devuelve graph.toJSON() de X6: {"cells":[...]}, donde las
+// aristas llevan shape "edge". También aceptamos el formato {"nodes":[],"edges":[]}
+// que usa el backend como diagrama vacío.
+
+type diagramResource struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+type diagramNodeData struct {
+	Text               string            `json:"text"`
+	Type               string            `json:"type"`
+	ContentTitle       string            `json:"contentTitle"`
+	ContentDescription string            `json:"contentDescription"`
+	Resources          []diagramResource `json:"resources"`
+}
+
+// diagramTerminal es el extremo de una arista: un id, {cell, port} o un punto suelto
+type diagramTerminal struct {
+	Cell string `json:"cell,omitempty"`
+	Port string `json:"port,omitempty"`
+}
+
+func (t *diagramTerminal) UnmarshalJSON(b []byte) error {
+	var id string
+	if err := json.Unmarshal(b, &id); err == nil {
+		t.Cell = id
+		return nil
+	}
+	var obj struct {
+		Cell interface{} `json:"cell"`
+		Port interface{} `json:"port"`
+	}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return err
+	}
+	if obj.Cell != nil {
+		t.Cell = fmt.Sprint(obj.Cell)
+	}
+	if obj.Port != nil {
+		t.Port = fmt.Sprint(obj.Port)
+	}
+	return nil
+}
+
+type diagramPosition struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type diagramSize struct {
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+type diagramCell struct {
+	// ubicación en el documento, p. ej. cells[3]
+	Path     string
+	ID       string
+	Shape    string
+	Parent   string
+	IsEdge   bool
+	Data     diagramNodeData
+	Position diagramPosition
+	Size     diagramSize
+	Source   diagramTerminal
+	Target   diagramTerminal
+	// campos originales de la celda, tal como llegaron
+	Raw map[string]json.RawMessage
+}
+
+type diagramDocument struct {
+	Nodes []*diagramCell
+	Edges []*diagramCell
+	// true si venía como {"cells": [...]}
+	cellsFormat bool
+	// claves de primer nivel distintas de cells/nodes/edges
+	extra map[string]json.RawMessage
+}
+
+func parseDiagram(s string) (*diagramDocument, error) {
+	doc := &diagramDocument{extra: map[string]json.RawMessage{}}
+	if strings.TrimSpace(s) == "" {
+		return doc, nil
+	}
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(s), &top); err != nil {
+		return nil, fmt.Errorf("json inválido: %w", err)
+	}
+	for k, v := range top {
+		switch k {
+		case "cells":
+			doc.cellsFormat = true
+			cells, err := parseCells(v, "cells", false)
+			if err != nil {
+				return nil, err
+			}
+			for _, c := range cells {
+				if c.IsEdge {
+					doc.Edges = append(doc.Edges, c)
+				} else {
+					doc.Nodes = append(doc.Nodes, c)
+				}
+			}
+		case "nodes", "edges":
+		default:
+			doc.extra[k] = v
+		}
+	}
+	if !doc.cellsFormat {
+		for _, k := range []string{"nodes", "edges"} {
+			v, ok := top[k]
+			if !ok {
+				continue
+			}
+			cells, err := parseCells(v, k, k == "edges")
+			if err != nil {
+				return nil, err
+			}
+			if k == "nodes" {
+				doc.Nodes = cells
+			} else {
+				doc.Edges = cells
+			}
+		}
+	}
+	return doc, nil
+}
+
+func parseCells(raw json.RawMessage, key string, edges bool) ([]*diagramCell, error) {
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return nil, nil
+	}
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("%s debe ser un arreglo de objetos", key)
+	}
+	out := make([]*diagramCell, 0, len(items))
+	for i, item := range items {
+		c := &diagramCell{Path: fmt.Sprintf("%s[%d]", key, i), Raw: item}
+		if v, ok := item["id"]; ok {
+			var id interface{}
+			if err := json.Unmarshal(v, &id); err == nil && id != nil {
+				c.ID = fmt.Sprint(id)
+			}
+		}
+		_ = unmarshalField(item, "shape", &c.Shape)
+		_ = unmarshalField(item, "parent", &c.Parent)
+		_ = unmarshalField(item, "position", &c.Position)
+		_ = unmarshalField(item, "size", &c.Size)
+		// data puede traer campos con tipos inesperados; nos quedamos con lo que se pueda leer
+		if v, ok := item["data"]; ok {
+			var fields map[string]json.RawMessage
+			if json.Unmarshal(v, &fields) == nil {
+				_ = unmarshalField(fields, "text", &c.Data.Text)
+				_ = unmarshalField(fields, "type", &c.Data.Type)
+				_ = unmarshalField(fields, "contentTitle", &c.Data.ContentTitle)
+				_ = unmarshalField(fields, "contentDescription", &c.Data.ContentDescription)
+				_ = unmarshalField(fields, "resources", &c.Data.Resources)
+			}
+		}
+		_, hasSource := item["source"]
+		_, hasTarget := item["target"]
+		c.IsEdge = edges || c.Shape == "edge" || hasSource || hasTarget
+		if c.IsEdge {
+			if err := unmarshalField(item, "source", &c.Source); err != nil {
+				return nil, fmt.Errorf("%s[%d].source inválido", key, i)
+			}
+			if err := unmarshalField(item, "target", &c.Target); err != nil {
+				return nil, fmt.Errorf("%s[%d].target inválido", key, i)
+			}
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func unmarshalField(m map[string]json.RawMessage, key string, dst interface{}) error {
+	v, ok := m[key]
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(v, dst)
+}
+
+// Label devuelve el texto visible de un nodo
+func (c *diagramCell) Label() string {
+	if c.Data.Text != "" {
+		return c.Data.Text
+	}
+	return c.Data.ContentTitle
+}
+
+// JSON vuelve a serializar el documento en el mismo formato en que llegó
+func (d *diagramDocument) JSON() (string, error) {
+	top := make(map[string]interface{}, len(d.extra)+2)
+	for k, v := range d.extra {
+		top[k] = v
+	}
+	raws := func(cells []*diagramCell) []map[string]json.RawMessage {
+		out := make([]map[string]json.RawMessage, 0, len(cells))
+		for _, c := range cells {
+			out = append(out, c.Raw)
+		}
+		return out
+	}
+	if d.cellsFormat {
+		top["cells"] = append(raws(d.Nodes), raws(d.Edges)...)
+	} else {
+		top["nodes"] = raws(d.Nodes)
+		top["edges"] = raws(d.Edges)
+	}
+	b, err := json.Marshal(top)
+	return string(b), err
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+type cellChange struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+type cellDiff struct {
+	ID      string       `json:"id"`
+	Type    string       `json:"type,omitempty"`
+	Text    string       `json:"text,omitempty"`
+	Source  string       `json:"source,omitempty"`
+	Target  string       `json:"target,omitempty"`
+	Changes []cellChange `json:"changes,omitempty"`
+}
+
+type cellSetDiff struct {
+	Added    []cellDiff `json:"added"`
+	Removed  []cellDiff `json:"removed"`
+	Modified []cellDiff `json:"modified"`
+}
+
+type diagramDiff struct {
+	Nodes cellSetDiff `json:"nodes"`
+	Edges cellSetDiff `json:"edges"`
+}
+
+func diffDiagrams(from, to *diagramDocument) *diagramDiff {
+	return &diagramDiff{
+		Nodes: diffCells(from.Nodes, to.Nodes),
+		Edges: diffCells(from.Edges, to.Edges),
+	}
+}
+
+func summarizeCell(c *diagramCell) cellDiff {
+	d := cellDiff{ID: c.ID}
+	if c.IsEdge {
+		d.Source, d.Target = c.Source.Cell, c.Target.Cell
+	} else {
+		d.Type, d.Text = c.Data.Type, c.Label()
+	}
+	return d
+}
+
+func diffCells(from, to []*diagramCell) cellSetDiff {
+	out := cellSetDiff{Added: []cellDiff{}, Removed: []cellDiff{}, Modified: []cellDiff{}}
+	before := map[string]*diagramCell{}
+	for _, c := range from {
+		before[c.ID] = c
+	}
+	seen := map[string]bool{}
+	for _, c := range to {
+		seen[c.ID] = true
+		old, ok := before[c.ID]
+		if !ok {
+			out.Added = append(out.Added, summarizeCell(c))
+			continue
+		}
+		if changes := cellChanges(old, c); len(changes) > 0 {
+			d := summarizeCell(c)
+			d.Changes = changes
+			out.Modified = append(out.Modified, d)
+		}
+	}
+	for _, c := range from {
+		if !seen[c.ID] {
+			out.Removed = append(out.Removed, summarizeCell(c))
+		}
+	}
+	return out
+}
+
+// cellChanges compara campo a campo; los de data se reportan por separado
+// (data.text, data.resources...) porque son los que edita el inspector.
+func cellChanges(a, b *diagramCell) []cellChange {
+	var changes []cellChange
+	for _, k := range unionKeys(a.Raw, b.Raw) {
+		if k == "id" {
+			continue
+		}
+		if k == "data" {
+			var da, db map[string]json.RawMessage
+			_ = json.Unmarshal(a.Raw[k], &da)
+			_ = json.Unmarshal(b.Raw[k], &db)
+			if da != nil || db != nil {
+				for _, dk := range unionKeys(da, db) {
+					if !sameJSON(da[dk], db[dk]) {
+						changes = append(changes, cellChange{Field: "data." + dk, Before: da[dk], After: db[dk]})
+					}
+				}
+				continue
+			}
+		}
+		if !sameJSON(a.Raw[k], b.Raw[k]) {
+			changes = append(changes, cellChange{Field: k, Before: a.Raw[k], After: b.Raw[k]})
+		}
+	}
+	return changes
+}
+
+func unionKeys(a, b map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sameJSON compara por valor, así el orden de claves o los espacios no cuentan como cambio
+func sameJSON(a, b json.RawMessage) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return string(a) == string(b)
+	}
+	return reflect.DeepEqual(va, vb)
+}
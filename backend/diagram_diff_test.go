@@ -0,0 +1,150 @@
+package main
+
+import (
+	"sort"
+	"testing"
+)
+
+func mustParseDiagram(t *testing.T, s string) *diagramDocument {
+	t.Helper()
+	doc, err := parseDiagram(s)
+	if err != nil {
+		t.Fatalf("parseDiagram: %v", err)
+	}
+	return doc
+}
+
+func diffIDs(list []cellDiff) []string {
+	ids := make([]string, 0, len(list))
+	for _, d := range list {
+		ids = append(ids, d.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func changedFields(d cellDiff) []string {
+	fields := make([]string, 0, len(d.Changes))
+	for _, ch := range d.Changes {
+		fields = append(fields, ch.Field)
+	}
+	return fields
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDiffDiagrams(t *testing.T) {
+	base := `{"cells":[
+		{"id":"a","shape":"rect","position":{"x":0,"y":0},"data":{"text":"A","type":"topic"}},
+		{"id":"b","shape":"rect","position":{"x":0,"y":100},"data":{"text":"B","type":"topic"}},
+		{"id":"e1","shape":"edge","source":"a","target":"b"}
+	]}`
+	tests := []struct {
+		name     string
+		from, to string
+		nodes    [3][]string // added, removed, modified
+		edges    [3][]string
+		fields   map[string][]string // campos cambiados por celda modificada
+	}{
+		{
+			name: "sin cambios",
+			from: base, to: base,
+		},
+		{
+			name: "orden de claves y espacios no cuentan",
+			from: base,
+			to: `{"cells":[
+				{"data":{"type":"topic","text":"A"},"position":{"y":0,"x":0},"shape":"rect","id":"a"},
+				{"id":"b","shape":"rect","position":{"x":0,"y":100},"data":{"text":"B","type":"topic"}},
+				{"id":"e1","shape":"edge","target":"b","source":"a"}
+			]}`,
+		},
+		{
+			name: "nodo y arista añadidos",
+			from: base,
+			to: `{"cells":[
+				{"id":"a","shape":"rect","position":{"x":0,"y":0},"data":{"text":"A","type":"topic"}},
+				{"id":"b","shape":"rect","position":{"x":0,"y":100},"data":{"text":"B","type":"topic"}},
+				{"id":"c","shape":"rect","data":{"text":"C","type":"subtopic"}},
+				{"id":"e1","shape":"edge","source":"a","target":"b"},
+				{"id":"e2","shape":"edge","source":"b","target":"c"}
+			]}`,
+			nodes: [3][]string{{"c"}, {}, {}},
+			edges: [3][]string{{"e2"}, {}, {}},
+		},
+		{
+			name:  "nodo y arista eliminados",
+			from:  base,
+			to:    `{"cells":[{"id":"a","shape":"rect","position":{"x":0,"y":0},"data":{"text":"A","type":"topic"}}]}`,
+			nodes: [3][]string{{}, {"b"}, {}},
+			edges: [3][]string{{}, {"e1"}, {}},
+		},
+		{
+			name: "modificados campo a campo, data por separado",
+			from: base,
+			to: `{"cells":[
+				{"id":"a","shape":"rect","position":{"x":50,"y":0},"data":{"text":"A2","type":"topic","contentTitle":"Nuevo"}},
+				{"id":"b","shape":"rect","position":{"x":0,"y":100},"data":{"text":"B","type":"topic"}},
+				{"id":"e1","shape":"edge","source":"a","target":{"cell":"b","port":"top"}}
+			]}`,
+			nodes:  [3][]string{{}, {}, {"a"}},
+			edges:  [3][]string{{}, {}, {"e1"}},
+			fields: map[string][]string{"a": {"data.contentTitle", "data.text", "position"}, "e1": {"target"}},
+		},
+		{
+			name:  "formato nodes/edges",
+			from:  `{"nodes":[],"edges":[]}`,
+			to:    `{"nodes":[{"id":"n1","data":{"text":"N","type":"topic"}}],"edges":[{"id":"x","source":"n1","target":"n1"}]}`,
+			nodes: [3][]string{{"n1"}, {}, {}},
+			edges: [3][]string{{"x"}, {}, {}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := diffDiagrams(mustParseDiagram(t, tt.from), mustParseDiagram(t, tt.to))
+			check := func(kind string, got cellSetDiff, want [3][]string) {
+				for i, list := range [][]cellDiff{got.Added, got.Removed, got.Modified} {
+					w := want[i]
+					if w == nil {
+						w = []string{}
+					}
+					if ids := diffIDs(list); !sameStrings(ids, w) {
+						t.Errorf("%s %s = %v; se esperaba %v", kind, []string{"added", "removed", "modified"}[i], ids, w)
+					}
+				}
+			}
+			check("nodes", d.Nodes, tt.nodes)
+			check("edges", d.Edges, tt.edges)
+			for _, m := range append(d.Nodes.Modified, d.Edges.Modified...) {
+				if want, ok := tt.fields[m.ID]; ok && !sameStrings(changedFields(m), want) {
+					t.Errorf("campos de %s = %v; se esperaba %v", m.ID, changedFields(m), want)
+				}
+			}
+		})
+	}
+}
+
+func TestDiffSummarizesCells(t *testing.T) {
+	from := mustParseDiagram(t, `{"cells":[]}`)
+	to := mustParseDiagram(t, `{"cells":[
+		{"id":"a","shape":"rect","data":{"contentTitle":"Título","type":"topic"}},
+		{"id":"e","shape":"edge","source":{"cell":"a"},"target":"a"}
+	]}`)
+	d := diffDiagrams(from, to)
+	if n := d.Nodes.Added[0]; n.Type != "topic" || n.Text != "Título" {
+		t.Errorf("nodo resumido = %+v", n)
+	}
+	if e := d.Edges.Added[0]; e.Source != "a" || e.Target != "a" {
+		t.Errorf("arista resumida = %+v", e)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DiagramJSON es el documento X6 tal como se guarda en la columna jsonb.
+// El string vacío se guarda como NULL, que jsonb no acepta "".
+type DiagramJSON string
+
+func (DiagramJSON) GormDataType() string {
+	return "jsonb"
+}
+
+func (d DiagramJSON) Value() (driver.Value, error) {
+	if strings.TrimSpace(string(d)) == "" {
+		return nil, nil
+	}
+	return string(d), nil
+}
+
+func (d *DiagramJSON) Scan(v interface{}) error {
+	switch x := v.(type) {
+	case nil:
+		*d = ""
+	case []byte:
+		*d = DiagramJSON(x)
+	case string:
+		*d = DiagramJSON(x)
+	default:
+		return fmt.Errorf("DiagramJSON: tipo no soportado %T", v)
+	}
+	return nil
+}
+
+// Document parsea el diagrama guardado
+func (d DiagramJSON) Document() (*diagramDocument, error) {
+	return parseDiagram(string(d))
+}
+
+// Consultas jsonpath sobre json_data. "$.*[*]" recorre tanto {"cells":[...]} como
+// {"nodes":[...],"edges":[...]}, así que sirven para los dos formatos. Las rutas van
+// siempre como parámetro: GORM tomaría el "?" de los filtros por un placeholder.
+const (
+	diagramNodesPath     = `$.*[*] ? (exists(@.data))`
+	diagramEdgesPath     = `$.*[*] ? (@.shape == "edge" || exists(@.source))`
+	diagramStepsPath     = `$.*[*] ? (@.data.type == "topic" || @.data.type == "subtopic")`
+	diagramResourcesPath = `$.*[*].data.resources[*]`
+)
+
+// diagramCountExpr cuenta los elementos de json_data que cumplen path
+func diagramCountExpr(path string) clause.Expr {
+	return gorm.Expr("COALESCE(jsonb_array_length(jsonb_path_query_array(roadmaps.json_data, ?::jsonpath)), 0)", path)
+}
+
+// jsonpathString cita s como literal de jsonpath (misma sintaxis que un string JSON)
+func jsonpathString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// DiagramMigrationFailure guarda las filas que no se pudieron convertir a jsonb
+type DiagramMigrationFailure struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	TableName string    `gorm:"size:64;not null" json:"table_name"`
+	RowID     uint      `gorm:"not null" json:"row_id"`
+	RawData   string    `gorm:"type:text" json:"raw_data"`
+	Error     string    `gorm:"type:text" json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// migrateDiagramColumns convierte json_data de text a jsonb antes del AutoMigrate.
+// Las filas que no son JSON válido se copian a diagram_migration_failures, se dejan
+// en NULL y se informan en el log.
+func migrateDiagramColumns(db *gorm.DB) error {
+	if err := db.AutoMigrate(&DiagramMigrationFailure{}); err != nil {
+		return err
+	}
+	for _, table := range []string{"roadmaps", "roadmap_versions"} {
+		var dataType string
+		db.Raw(`SELECT data_type FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = ? AND column_name = 'json_data'`, table).Scan(&dataType)
+		if dataType != "text" {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var rows []struct {
+				ID       uint
+				JSONData string
+			}
+			if err := tx.Table(table).Select("id, json_data").Where("json_data IS NOT NULL").Find(&rows).Error; err != nil {
+				return err
+			}
+			converted, failed := 0, []uint{}
+			for _, row := range rows {
+				raw := strings.TrimSpace(row.JSONData)
+				if raw == "" {
+					if err := tx.Table(table).Where("id = ?", row.ID).Update("json_data", nil).Error; err != nil {
+						return err
+					}
+					continue
+				}
+				// jsonb rechaza \u0000 aunque sea JSON válido
+				var reason string
+				if !json.Valid([]byte(raw)) {
+					reason = "json inválido"
+				} else if bytes.Contains([]byte(raw), []byte(`\u0000`)) {
+					reason = "contiene \\u0000, no admitido por jsonb"
+				}
+				if reason == "" {
+					converted++
+					continue
+				}
+				failed = append(failed, row.ID)
+				if err := tx.Create(&DiagramMigrationFailure{TableName: table, RowID: row.ID, RawData: row.JSONData, Error: reason}).Error; err != nil {
+					return err
+				}
+				if err := tx.Table(table).Where("id = ?", row.ID).Update("json_data", nil).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN json_data TYPE jsonb USING json_data::jsonb`, table)).Error; err != nil {
+				return err
+			}
+			log.Printf("migración jsonb de %s: %d filas convertidas, %d fallidas %v (ver diagram_migration_failures)", table, converted, len(failed), failed)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("migrando %s.json_data a jsonb: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func registerDiagramQueryRoutes(api fiber.Router, db *gorm.DB, jwtSecret string) {
+	api.Get("/learning-paths/:id/diagram/stats", func(c *fiber.Ctx) error {
+		claims, err := optionalAuth(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var r Roadmap
+		if err := db.First(&r, id).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		if !canAccess(db, claimsUserID(claims), &r, actionRead) {
+			return denyAccess(c, claims)
+		}
+		var byType []struct {
+			Type  string
+			Count int64
+		}
+		if err := db.Raw(`SELECT t #>> '{}' AS type, COUNT(*) AS count
+			FROM roadmaps, jsonb_path_query(roadmaps.json_data, '$.*[*].data.type') AS t
+			WHERE roadmaps.id = ? GROUP BY 1 ORDER BY 1`, r.ID).Scan(&byType).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo calcular"})
+		}
+		var totals struct {
+			Nodes      int64
+			Edges      int64
+			Resources  int64
+			StepsCount int64
+		}
+		if err := db.Raw(`SELECT ? AS nodes, ? AS edges, ? AS resources, ? AS steps_count FROM roadmaps WHERE roadmaps.id = ?`,
+			diagramCountExpr(diagramNodesPath), diagramCountExpr(diagramEdgesPath),
+			diagramCountExpr(diagramResourcesPath), diagramCountExpr(diagramStepsPath), r.ID).Scan(&totals).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo calcular"})
+		}
+		types := fiber.Map{}
+		for _, t := range byType {
+			types[t.Type] = t.Count
+		}
+		return c.JSON(fiber.Map{"nodes": totals.Nodes, "edges": totals.Edges, "resources": totals.Resources, "stepsCount": totals.StepsCount, "byType": types})
+	})
+
+	// qué roadmaps (visibles para quien pregunta) enlazan un recurso concreto
+	api.Get("/resources/usage", func(c *fiber.Ctx) error {
+		claims, err := optionalAuth(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		url := strings.TrimSpace(c.Query("url"))
+		if url == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "falta url"})
+		}
+		path := fmt.Sprintf(`$.*[*].data.resources[*] ? (@.url == %s)`, jsonpathString(url))
+		var list []Roadmap
+		if err := db.Scopes(scopeVisibleTo(claimsUserID(claims))).
+			Where("jsonb_path_exists(roadmaps.json_data, ?::jsonpath)", path).
+			Order("roadmaps.created_at desc").Limit(100).Find(&list).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"items": []fiber.Map{}})
+		}
+		items := make([]fiber.Map, 0, len(list))
+		for _, r := range list {
+			items = append(items, fiber.Map{"id": r.ID, "title": r.Title, "visibility": r.Visibility})
+		}
+		return c.JSON(fiber.Map{"items": items})
+	})
+}
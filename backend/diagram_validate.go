@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+const (
+	maxDiagramBytes      = 2 << 20
+	maxDiagramCells      = 2000
+	maxCellIDLen         = 128 // NodeID de path_steps, node_progress y step_comments
+	maxNodeTextLen       = 500
+	maxNodeDescLen       = 20000
+	maxNodeResources     = 50
+	maxResourceURLLen    = 2048
+	maxDiagramIssuesSent = 50
+)
+
+// tipos de nodo que ofrece el editor (createNodeByType en roadmap-editor.page.ts)
+var diagramNodeTypes = map[string]bool{
+	"title":     true,
+	"topic":     true,
+	"subtopic":  true,
+	"paragraph": true,
+	"label":     true,
+	"section":   true,
+}
+
+type diagramIssue struct {
+	CellID  string `json:"cellId,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+// validateDiagram parsea y valida el documento; si hay problemas el documento es nil
+func validateDiagram(raw string) (*diagramDocument, []diagramIssue) {
+	if len(raw) > maxDiagramBytes {
+		return nil, []diagramIssue{{Message: fmt.Sprintf("el diagrama supera el máximo de %d bytes", maxDiagramBytes)}}
+	}
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(raw), &top); err != nil {
+		return nil, []diagramIssue{{Message: "el diagrama no es un objeto JSON válido"}}
+	}
+	_, hasCells := top["cells"]
+	_, hasNodes := top["nodes"]
+	_, hasEdges := top["edges"]
+	if !hasCells && !hasNodes && !hasEdges {
+		return nil, []diagramIssue{{Message: "se esperaba cells o nodes/edges"}}
+	}
+	doc, err := parseDiagram(raw)
+	if err != nil {
+		return nil, []diagramIssue{{Message: err.Error()}}
+	}
+	if n := len(doc.Nodes) + len(doc.Edges); n > maxDiagramCells {
+		return nil, []diagramIssue{{Message: fmt.Sprintf("el diagrama tiene %d celdas; el máximo es %d", n, maxDiagramCells)}}
+	}
+
+	var issues []diagramIssue
+	add := func(c *diagramCell, field, msg string) {
+		path := c.Path
+		if field != "" {
+			path += "." + field
+		}
+		issues = append(issues, diagramIssue{CellID: c.ID, Path: path, Message: msg})
+	}
+
+	nodes := map[string]bool{}
+	seen := map[string]bool{}
+	for _, c := range append(append([]*diagramCell{}, doc.Nodes...), doc.Edges...) {
+		if c.ID == "" {
+			add(c, "id", "falta el id de la celda")
+			continue
+		}
+		if utf8.RuneCountInString(c.ID) > maxCellIDLen {
+			add(c, "id", fmt.Sprintf("máximo %d caracteres", maxCellIDLen))
+		}
+		if seen[c.ID] {
+			add(c, "id", "id duplicado")
+		}
+		seen[c.ID] = true
+		if !c.IsEdge {
+			nodes[c.ID] = true
+		}
+	}
+
+	for _, c := range doc.Nodes {
+		validateNodeData(c, add)
+		if c.Parent != "" && !nodes[c.Parent] {
+			add(c, "parent", "el nodo padre no existe")
+		}
+	}
+	for _, c := range doc.Edges {
+		if c.Source.Cell == "" {
+			add(c, "source", "la arista no está conectada a un nodo")
+		} else if !nodes[c.Source.Cell] {
+			add(c, "source", fmt.Sprintf("el nodo %q no existe", c.Source.Cell))
+		}
+		if c.Target.Cell == "" {
+			add(c, "target", "la arista no está conectada a un nodo")
+		} else if !nodes[c.Target.Cell] {
+			add(c, "target", fmt.Sprintf("el nodo %q no existe", c.Target.Cell))
+		}
+	}
+
+	if len(issues) > 0 {
+		if len(issues) > maxDiagramIssuesSent {
+			issues = issues[:maxDiagramIssuesSent]
+		}
+		return nil, issues
+	}
+	return doc, nil
+}
+
+func validateNodeData(c *diagramCell, add func(c *diagramCell, field, msg string)) {
+	raw, ok := c.Raw["data"]
+	if !ok {
+		add(c, "data", "el nodo no tiene data")
+		return
+	}
+	// parseDiagram es tolerante con los tipos; aquí exigimos los correctos
+	var data diagramNodeData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		if te, ok := err.(*json.UnmarshalTypeError); ok && te.Field != "" {
+			add(c, "data."+te.Field, fmt.Sprintf("se esperaba %s", te.Type))
+			return
+		}
+		add(c, "data", "data tiene un formato inválido")
+		return
+	}
+	if data.Type == "" {
+		add(c, "data.type", "falta el tipo de nodo")
+	} else if !diagramNodeTypes[data.Type] {
+		add(c, "data.type", fmt.Sprintf("tipo de nodo desconocido %q", data.Type))
+	}
+	if utf8.RuneCountInString(data.Text) > maxNodeTextLen {
+		add(c, "data.text", fmt.Sprintf("máximo %d caracteres", maxNodeTextLen))
+	}
+	if utf8.RuneCountInString(data.ContentTitle) > maxNodeTextLen {
+		add(c, "data.contentTitle", fmt.Sprintf("máximo %d caracteres", maxNodeTextLen))
+	}
+	if utf8.RuneCountInString(data.ContentDescription) > maxNodeDescLen {
+		add(c, "data.contentDescription", fmt.Sprintf("máximo %d caracteres", maxNodeDescLen))
+	}
+	if len(data.Resources) > maxNodeResources {
+		add(c, "data.resources", fmt.Sprintf("máximo %d recursos por nodo", maxNodeResources))
+	}
+	for i, r := range data.Resources {
+		if utf8.RuneCountInString(r.Title) > maxNodeTextLen || len(r.URL) > maxResourceURLLen {
+			add(c, fmt.Sprintf("data.resources[%d]", i), "recurso demasiado largo")
+		}
+	}
+}
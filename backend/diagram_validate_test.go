@@ -0,0 +1,161 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateDiagramValid(t *testing.T) {
+	raw := `{"cells":[
+		{"id":"s","shape":"rect","data":{"text":"Sección","type":"section"}},
+		{"id":"a","shape":"rect","parent":"s","data":{"text":"A","type":"topic","resources":[{"title":"Docs","url":"https://example.com"}]}},
+		{"id":"b","shape":"rect","data":{"text":"B","type":"subtopic"}},
+		{"id":"e1","shape":"edge","source":{"cell":"a","port":"bottom"},"target":"b"}
+	]}`
+	doc, issues := validateDiagram(raw)
+	if len(issues) > 0 {
+		t.Fatalf("issues inesperados: %+v", issues)
+	}
+	if doc == nil || len(doc.Nodes) != 3 || len(doc.Edges) != 1 {
+		t.Fatalf("documento = %+v", doc)
+	}
+}
+
+func TestValidateDiagramIssues(t *testing.T) {
+	node := func(id, extra string) string {
+		return `{"id":"` + id + `","shape":"rect","data":{"text":"x","type":"topic"}` + extra + `}`
+	}
+	tests := []struct {
+		name string
+		raw  string
+		want diagramIssue
+	}{
+		{
+			name: "id duplicado",
+			raw:  `{"cells":[` + node("a", "") + `,` + node("a", "") + `]}`,
+			want: diagramIssue{CellID: "a", Path: "cells[1].id", Message: "id duplicado"},
+		},
+		{
+			name: "id duplicado entre nodo y arista",
+			raw:  `{"cells":[` + node("a", "") + `,{"id":"a","shape":"edge","source":"a","target":"a"}]}`,
+			want: diagramIssue{CellID: "a", Path: "cells[1].id", Message: "id duplicado"},
+		},
+		{
+			name: "falta el id",
+			raw:  `{"cells":[{"shape":"rect","data":{"type":"topic"}}]}`,
+			want: diagramIssue{Path: "cells[0].id", Message: "falta el id de la celda"},
+		},
+		{
+			name: "id demasiado largo",
+			raw:  `{"cells":[` + node(strings.Repeat("n", maxCellIDLen+1), "") + `]}`,
+			want: diagramIssue{CellID: strings.Repeat("n", maxCellIDLen+1), Path: "cells[0].id", Message: "máximo 128 caracteres"},
+		},
+		{
+			name: "arista sin origen",
+			raw:  `{"cells":[` + node("a", "") + `,{"id":"e","shape":"edge","target":"a"}]}`,
+			want: diagramIssue{CellID: "e", Path: "cells[1].source", Message: "la arista no está conectada a un nodo"},
+		},
+		{
+			name: "arista con destino inexistente",
+			raw:  `{"cells":[` + node("a", "") + `,{"id":"e","shape":"edge","source":"a","target":{"cell":"zz"}}]}`,
+			want: diagramIssue{CellID: "e", Path: "cells[1].target", Message: `el nodo "zz" no existe`},
+		},
+		{
+			name: "arista que apunta a otra arista",
+			raw:  `{"cells":[` + node("a", "") + `,{"id":"e1","shape":"edge","source":"a","target":"a"},{"id":"e2","shape":"edge","source":"a","target":"e1"}]}`,
+			want: diagramIssue{CellID: "e2", Path: "cells[2].target", Message: `el nodo "e1" no existe`},
+		},
+		{
+			name: "arista colgante en formato nodes/edges",
+			raw:  `{"nodes":[` + node("a", "") + `],"edges":[{"id":"e","source":"a","target":"b"}]}`,
+			want: diagramIssue{CellID: "e", Path: "edges[0].target", Message: `el nodo "b" no existe`},
+		},
+		{
+			name: "padre inexistente",
+			raw:  `{"cells":[` + node("a", `,"parent":"p"`) + `]}`,
+			want: diagramIssue{CellID: "a", Path: "cells[0].parent", Message: "el nodo padre no existe"},
+		},
+		{
+			name: "tipo de nodo desconocido",
+			raw:  `{"cells":[{"id":"a","shape":"rect","data":{"type":"hexagon"}}]}`,
+			want: diagramIssue{CellID: "a", Path: "cells[0].data.type", Message: `tipo de nodo desconocido "hexagon"`},
+		},
+		{
+			name: "falta el tipo de nodo",
+			raw:  `{"cells":[{"id":"a","shape":"rect","data":{"text":"A"}}]}`,
+			want: diagramIssue{CellID: "a", Path: "cells[0].data.type", Message: "falta el tipo de nodo"},
+		},
+		{
+			name: "nodo sin data",
+			raw:  `{"cells":[{"id":"a","shape":"rect"}]}`,
+			want: diagramIssue{CellID: "a", Path: "cells[0].data", Message: "el nodo no tiene data"},
+		},
+		{
+			name: "tipo incorrecto en data",
+			raw:  `{"cells":[{"id":"a","shape":"rect","data":{"type":"topic","text":5}}]}`,
+			want: diagramIssue{CellID: "a", Path: "cells[0].data.text", Message: "se esperaba string"},
+		},
+		{
+			name: "texto demasiado largo",
+			raw:  `{"cells":[{"id":"a","shape":"rect","data":{"type":"topic","text":"` + strings.Repeat("ñ", maxNodeTextLen+1) + `"}}]}`,
+			want: diagramIssue{CellID: "a", Path: "cells[0].data.text", Message: "máximo 500 caracteres"},
+		},
+		{
+			name: "no es un objeto",
+			raw:  `[]`,
+			want: diagramIssue{Message: "el diagrama no es un objeto JSON válido"},
+		},
+		{
+			name: "sin cells ni nodes/edges",
+			raw:  `{"foo":[]}`,
+			want: diagramIssue{Message: "se esperaba cells o nodes/edges"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, issues := validateDiagram(tt.raw)
+			if doc != nil {
+				t.Error("con problemas el documento debería ser nil")
+			}
+			if len(issues) != 1 {
+				t.Fatalf("issues = %+v; se esperaba solo %+v", issues, tt.want)
+			}
+			if issues[0] != tt.want {
+				t.Fatalf("issue = %+v; se esperaba %+v", issues[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateDiagramLimits(t *testing.T) {
+	if _, issues := validateDiagram(`{"cells":[],"x":"` + strings.Repeat("a", maxDiagramBytes) + `"}`); len(issues) != 1 || !strings.Contains(issues[0].Message, "bytes") {
+		t.Fatalf("tamaño: issues = %+v", issues)
+	}
+
+	var b strings.Builder
+	b.WriteString(`{"cells":[`)
+	for i := 0; i <= maxDiagramCells; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(`{"id":"n","data":{"type":"topic"}}`)
+	}
+	b.WriteString(`]}`)
+	if _, issues := validateDiagram(b.String()); len(issues) != 1 || !strings.Contains(issues[0].Message, "celdas") {
+		t.Fatalf("celdas: issues = %+v", issues)
+	}
+
+	// muchos errores se recortan a maxDiagramIssuesSent
+	b.Reset()
+	b.WriteString(`{"cells":[`)
+	for i := 0; i < maxDiagramIssuesSent*2; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(`{"shape":"rect"}`)
+	}
+	b.WriteString(`]}`)
+	if _, issues := validateDiagram(b.String()); len(issues) != maxDiagramIssuesSent {
+		t.Fatalf("se devolvieron %d issues; se esperaba %d", len(issues), maxDiagramIssuesSent)
+	}
+}
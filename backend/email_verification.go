@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	emailVerificationTTL    = 48 * time.Hour
+	verificationResendEvery = 2 * time.Minute
+)
+
+// mismo patrón que el CHECK de users.email en db.sql
+var emailPattern = regexp.MustCompile(`^[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}$`)
+
+var errVerificationToken = errors.New("enlace de verificación inválido o vencido")
+
+// requireVerifiedEmail exige correo verificado para publicar, comentar y valorar;
+// REQUIRE_VERIFIED_EMAIL=false lo desactiva (p. ej. en desarrollo sin SMTP)
+var requireVerifiedEmail = os.Getenv("REQUIRE_VERIFIED_EMAIL") != "false"
+
+type verificationClaims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// la clave se deriva del secreto para que el enlace no sirva como access token
+func verificationKey(secret string) []byte {
+	return []byte("email-verification:" + secret)
+}
+
+func makeVerificationToken(secret string, u *User) (string, error) {
+	claims := verificationClaims{
+		Email: u.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(u.ID), 10),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(emailVerificationTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(verificationKey(secret))
+}
+
+func parseVerificationToken(secret, token string) (*verificationClaims, error) {
+	parsed, err := jwt.ParseWithClaims(token, &verificationClaims{}, func(t *jwt.Token) (interface{}, error) {
+		return verificationKey(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	if claims, ok := parsed.Claims.(*verificationClaims); ok && parsed.Valid {
+		return claims, nil
+	}
+	return nil, fmt.Errorf("invalid verification token")
+}
+
+// sendVerificationEmail manda el enlace firmado; el token no se guarda en la base
+func sendVerificationEmail(mailer Mailer, secret, appURL string, u *User) error {
+	token, err := makeVerificationToken(secret, u)
+	if err != nil {
+		return err
+	}
+	link := fmt.Sprintf("%s/verificar?token=%s", strings.TrimRight(appURL, "/"), token)
+	sendMailAsync(mailer, u.Email, "Confirma tu correo de Cartesia", fmt.Sprintf(
+		"Hola %s,\n\nConfirma tu dirección de correo abriendo este enlace (vence en 48 horas):\n\n%s\n\nSi no creaste una cuenta en Cartesia, ignora este correo.\n",
+		u.Username, link))
+	return nil
+}
+
+// migrateEmailVerification añade email_verified a una tabla users ya existente y da
+// por verificadas las cuentas anteriores; las nuevas empiezan sin verificar.
+func migrateEmailVerification(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(&User{}) || m.HasColumn(&User{}, "EmailVerified") {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Migrator().AddColumn(&User{}, "EmailVerified"); err != nil {
+			return err
+		}
+		return tx.Exec("UPDATE users SET email_verified = true").Error
+	})
+}
+
+// emailVerified dice si userID puede hacer acciones que exigen correo verificado
+func emailVerified(db *gorm.DB, userID uint) bool {
+	if !requireVerifiedEmail {
+		return true
+	}
+	var verified bool
+	db.Model(&User{}).Where("id = ?", userID).Select("email_verified").Scan(&verified)
+	return verified
+}
+
+// denyUnverified responde 403 con un código que el frontend usa para ofrecer el reenvío
+func denyUnverified(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "confirma tu correo electrónico para hacer esto", "code": "email_unverified"})
+}
+
+func registerEmailVerificationRoutes(api fiber.Router, db *gorm.DB, jwtSecret string, mailer Mailer, appURL string) {
+	// no pide sesión: el enlace puede abrirse en otro dispositivo
+	api.Post("/auth/verify", func(c *fiber.Ctx) error {
+		var body struct {
+			Token string `json:"token"`
+		}
+		if err := c.BodyParser(&body); err != nil || body.Token == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payload inválido"})
+		}
+		claims, err := parseVerificationToken(jwtSecret, body.Token)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": errVerificationToken.Error()})
+		}
+		// si el usuario cambió de correo, los enlaces anteriores dejan de valer
+		res := db.Model(&User{}).Where("id = ? AND email = ?", claims.Subject, claims.Email).Update("email_verified", true)
+		if res.Error != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo verificar"})
+		}
+		if res.RowsAffected == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": errVerificationToken.Error()})
+		}
+		return c.JSON(fiber.Map{"ok": true})
+	})
+
+	// reenvía el enlace como mucho una vez cada verificationResendEvery
+	api.Post("/auth/verify/resend", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		var u User
+		if err := db.First(&u, claims.UserID).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		if u.EmailVerified {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "el correo ya está verificado"})
+		}
+		now := time.Now()
+		// la condición va en el UPDATE para que dos peticiones simultáneas no envíen dos correos
+		res := db.Model(&User{}).Where("id = ? AND (verification_sent_at IS NULL OR verification_sent_at <= ?)", u.ID, now.Add(-verificationResendEvery)).
+			Update("verification_sent_at", now)
+		if res.Error != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo enviar"})
+		}
+		if res.RowsAffected == 0 {
+			wait := verificationResendEvery
+			if u.VerificationSentAt != nil {
+				wait = time.Until(u.VerificationSentAt.Add(verificationResendEvery))
+			}
+			return tooManyRequests(c, wait, "espera antes de pedir otro correo", "")
+		}
+		if err := sendVerificationEmail(mailer, jwtSecret, appURL, &u); err != nil {
+			log.Printf("no se pudo generar el enlace de verificación del usuario %d: %v", u.ID, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo enviar"})
+		}
+		return c.JSON(fiber.Map{"ok": true})
+	})
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// RoadmapBranch registra que ChildRoadmapID es un fork de ParentRoadmapID
+// (roadmaps_branches en db.sql). BaseJSON/BaseRevision son el diagrama del padre
+// en el último punto común: el fork y, más adelante, cada sincronización.
+type RoadmapBranch struct {
+	ID              uint        `gorm:"primaryKey" json:"id"`
+	ParentRoadmapID uint        `gorm:"not null;index" json:"parent_roadmap_id"`
+	ChildRoadmapID  uint        `gorm:"not null;uniqueIndex" json:"child_roadmap_id"`
+	ForkedBy        uint        `gorm:"not null" json:"forked_by"`
+	BaseRevision    uint        `gorm:"not null;default:0" json:"base_revision"`
+	BaseJSON        DiagramJSON `gorm:"type:jsonb" json:"-"`
+	CreatedAt       time.Time   `json:"created_at"`
+}
+
+// TableName usa el nombre de db.sql; GORM lo llamaría roadmap_branches
+func (RoadmapBranch) TableName() string {
+	return "roadmaps_branches"
+}
+
+func registerForkRoutes(api fiber.Router, db *gorm.DB, jwtSecret string) {
+	// copia título, descripción, etiquetas y diagrama en un roadmap privado del usuario
+	api.Post("/learning-paths/:id/fork", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var parent Roadmap
+		if err := db.First(&parent, id).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		if !canAccess(db, claims.UserID, &parent, actionRead) {
+			return denyAccess(c, claims)
+		}
+		var body struct {
+			Title string `json:"title"`
+		}
+		_ = c.BodyParser(&body)
+		title := strings.TrimSpace(body.Title)
+		if title == "" {
+			title = parent.Title
+		}
+		child := &Roadmap{Title: title, Description: parent.Description, Visibility: "private"}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(child).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&UserRoadmap{UserID: claims.UserID, RoadmapID: child.ID}).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&Collaboration{RoadmapID: child.ID, CollaboratorID: claims.UserID, Role: roleOwner}).Error; err != nil {
+				return err
+			}
+			if tags := roadmapTagNames(tx, []uint{parent.ID})[parent.ID]; len(tags) > 0 {
+				if err := setRoadmapTags(tx, child.ID, tags); err != nil {
+					return err
+				}
+			}
+			if err := tx.Create(&RoadmapBranch{ParentRoadmapID: parent.ID, ChildRoadmapID: child.ID, ForkedBy: claims.UserID,
+				BaseRevision: parent.Revision, BaseJSON: parent.JSONData}).Error; err != nil {
+				return err
+			}
+			if parent.JSONData == "" {
+				return nil
+			}
+			_, err := saveDiagram(tx, child, diagramSave{AuthorID: claims.UserID, JSON: string(parent.JSONData)})
+			return err
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo crear el fork"})
+		}
+		notifyOwners(db, &parent, claims.UserID, notifyFork, func(actor, title string) string {
+			return fmt.Sprintf("%s hizo un fork de «%s»", actor, title)
+		})
+		return c.JSON(fiber.Map{"id": child.ID, "title": child.Title, "description": child.Description, "visibility": child.Visibility,
+			"createdAt": child.CreatedAt, "forkedFrom": parent.ID})
+	})
+
+	// forks del roadmap que el usuario puede ver, con el mismo sobre que los listados
+	api.Get("/learning-paths/:id/forks", func(c *fiber.Ctx) error {
+		claims, err := optionalAuth(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var parent Roadmap
+		if err := db.First(&parent, id).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		if !canAccess(db, claimsUserID(claims), &parent, actionRead) {
+			return denyAccess(c, claims)
+		}
+		l, err := parseRoadmapListing(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return l.respond(c, db, func() *gorm.DB {
+			return db.Model(&Roadmap{}).Scopes(scopeVisibleTo(claimsUserID(claims))).
+				Where("EXISTS (SELECT 1 FROM roadmaps_branches rb WHERE rb.child_roadmap_id = roadmaps.id AND rb.parent_roadmap_id = ?)", parent.ID)
+		}, nil)
+	})
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// sortByRelevance ordena por ts_rank; es el orden por defecto de una búsqueda con texto
+// y en los listados sin texto equivale a created_at
+const sortByRelevance = "relevance"
+
+// columnas por las que se puede ordenar un listado de roadmaps
+var roadmapSortColumns = map[string]string{
+	"created_at":  "roadmaps.created_at",
+	"updated_at":  "roadmaps.updated_at",
+	"title":       "lower(roadmaps.title)",
+	"steps_count": "steps_count",
+}
+
+// pageParams lee page/pageSize con valores por defecto y tope
+func pageParams(c *fiber.Ctx) (int, int) {
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	size := c.QueryInt("pageSize", defaultPageSize)
+	if size < 1 {
+		size = defaultPageSize
+	}
+	if size > maxPageSize {
+		size = maxPageSize
+	}
+	return page, size
+}
+
+// queryUint lee un parámetro numérico opcional; ok es false si viene mal formado
+func queryUint(c *fiber.Ctx, key string) (v *int, ok bool) {
+	s := strings.TrimSpace(c.Query(key))
+	if s == "" {
+		return nil, true
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return nil, false
+	}
+	return &n, true
+}
+
+// queryDate acepta RFC3339 o YYYY-MM-DD; endOfDay lleva las fechas sin hora al día siguiente
+// para que un rango "hasta 2024-05-01" incluya ese día.
+func queryDate(c *fiber.Ctx, key string, endOfDay bool) (*time.Time, error) {
+	s := strings.TrimSpace(c.Query(key))
+	if s == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return nil, errors.New(key + " inválido")
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// roadmapListing son los parámetros comunes de paginación, orden y filtro de
+// los listados de roadmaps; todos responden con el mismo sobre.
+type roadmapListing struct {
+	Page        int
+	PageSize    int
+	SortBy      string
+	SortDir     string
+	Visibility  string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Tag         string
+}
+
+func parseRoadmapListing(c *fiber.Ctx) (*roadmapListing, error) {
+	l := &roadmapListing{SortBy: c.Query("sortBy"), SortDir: strings.ToUpper(c.Query("sortDir", "DESC"))}
+	l.Page, l.PageSize = pageParams(c)
+	if _, ok := roadmapSortColumns[l.SortBy]; !ok && l.SortBy != "" && l.SortBy != sortByRelevance {
+		return nil, errors.New("sortBy inválido")
+	}
+	if l.SortDir != "ASC" && l.SortDir != "DESC" {
+		return nil, errors.New("sortDir inválido")
+	}
+	switch v := strings.ToLower(strings.TrimSpace(c.Query("visibility"))); v {
+	case "", "public", "private":
+		l.Visibility = v
+	default:
+		return nil, errors.New("visibility inválido")
+	}
+	var err error
+	if l.CreatedFrom, err = queryDate(c, "createdFrom", false); err != nil {
+		return nil, err
+	}
+	if l.CreatedTo, err = queryDate(c, "createdTo", true); err != nil {
+		return nil, err
+	}
+	if t := c.Query("tag"); t != "" {
+		if l.Tag = normalizeTag(t); l.Tag == "" {
+			return nil, errInvalidTag
+		}
+	}
+	return l, nil
+}
+
+func (l *roadmapListing) filter(q *gorm.DB) *gorm.DB {
+	if l.Visibility != "" {
+		q = q.Where("roadmaps.visibility = ?", l.Visibility)
+	}
+	if l.CreatedFrom != nil {
+		q = q.Where("roadmaps.created_at >= ?", *l.CreatedFrom)
+	}
+	if l.CreatedTo != nil {
+		q = q.Where("roadmaps.created_at < ?", *l.CreatedTo)
+	}
+	if l.Tag != "" {
+		q = q.Scopes(scopeTagged(l.Tag))
+	}
+	return q
+}
+
+// respond cuenta y pagina base (ya restringida a lo que el usuario puede ver).
+// rank, si no es nil, es el orden por defecto y desempata los demás.
+func (l *roadmapListing) respond(c *fiber.Ctx, db *gorm.DB, base func() *gorm.DB, rank *clause.Expr) error {
+	var total int64
+	if err := l.filter(base()).Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo listar"})
+	}
+	var rows []struct {
+		ID             uint
+		Title          string
+		Description    string
+		Visibility     string
+		CreatedAt      time.Time
+		UpdatedAt      time.Time
+		StepsCount     int64
+		ResourcesCount int64
+		ForksCount     int64
+		ForkedFrom     *uint
+	}
+	cols := `roadmaps.id, roadmaps.title, roadmaps.description, roadmaps.visibility, roadmaps.created_at, roadmaps.updated_at,
+		? AS steps_count, ? AS resources_count,
+		(SELECT COUNT(*) FROM roadmaps_branches rb WHERE rb.parent_roadmap_id = roadmaps.id) AS forks_count,
+		(SELECT rb.parent_roadmap_id FROM roadmaps_branches rb WHERE rb.child_roadmap_id = roadmaps.id) AS forked_from`
+	vars := []interface{}{diagramCountExpr(diagramStepsPath), diagramCountExpr(diagramResourcesPath)}
+	if l.SortBy == "" || l.SortBy == sortByRelevance {
+		l.SortBy = "created_at"
+		if rank != nil {
+			l.SortBy = sortByRelevance
+		}
+	}
+	var order string
+	if rank != nil {
+		cols += ", ? AS rank"
+		vars = append(vars, *rank)
+	}
+	switch {
+	case l.SortBy == sortByRelevance:
+		order = "rank " + l.SortDir + ", roadmaps.created_at DESC"
+	case rank != nil:
+		order = roadmapSortColumns[l.SortBy] + " " + l.SortDir + ", rank DESC"
+	default:
+		order = roadmapSortColumns[l.SortBy] + " " + l.SortDir
+	}
+	q := l.filter(base()).Select(cols, vars...).Order(order + ", roadmaps.id DESC")
+	if err := q.Limit(l.PageSize).Offset((l.Page - 1) * l.PageSize).Scan(&rows).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo listar"})
+	}
+	ids := make([]uint, 0, len(rows))
+	for _, r := range rows {
+		ids = append(ids, r.ID)
+	}
+	tags := roadmapTagNames(db, ids)
+	items := make([]fiber.Map, 0, len(rows))
+	for _, r := range rows {
+		items = append(items, fiber.Map{"id": r.ID, "title": r.Title, "description": r.Description, "visibility": r.Visibility,
+			"createdAt": r.CreatedAt, "updatedAt": r.UpdatedAt, "stepsCount": r.StepsCount, "resourcesCount": r.ResourcesCount,
+			"forksCount": r.ForksCount, "forkedFrom": r.ForkedFrom, "tags": tags[r.ID]})
+	}
+	return c.JSON(fiber.Map{"items": items, "page": l.Page, "pageSize": l.PageSize, "total": total, "sortBy": l.SortBy, "sortDir": l.SortDir})
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// duración del lease; el editor lo renueva llamando de nuevo a /lock
+const lockTTL = 2 * time.Minute
+
+// lockedByOther indica si otro usuario tiene un lease vigente sobre el roadmap
+func lockedByOther(r *Roadmap, userID uint, now time.Time) bool {
+	return r.LockedBy != nil && *r.LockedBy != userID && r.LockExpiresAt != nil && r.LockExpiresAt.After(now)
+}
+
+// lockHolderJSON describe el lease actual (o nil si no hay ninguno vigente)
+func lockHolderJSON(db *gorm.DB, r *Roadmap) fiber.Map {
+	if r.LockedBy == nil || r.LockExpiresAt == nil || !r.LockExpiresAt.After(time.Now()) {
+		return nil
+	}
+	var u User
+	db.Select("id", "username").First(&u, *r.LockedBy)
+	return fiber.Map{"userId": *r.LockedBy, "username": u.Username, "expiresAt": *r.LockExpiresAt}
+}
+
+// releaseExpiredLocks limpia periódicamente los leases vencidos
+func releaseExpiredLocks(db *gorm.DB, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for range t.C {
+		if err := db.Model(&Roadmap{}).Where("lock_expires_at <= ?", time.Now()).
+			UpdateColumns(map[string]interface{}{"locked_by": nil, "lock_expires_at": nil}).Error; err != nil {
+			log.Printf("no se pudieron liberar locks vencidos: %v", err)
+		}
+	}
+}
+
+func registerLockRoutes(api fiber.Router, db *gorm.DB, jwtSecret string) {
+	api.Get("/learning-paths/:id/lock", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var r Roadmap
+		if err := db.First(&r, id).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		if !canAccess(db, claims.UserID, &r, actionRead) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden"})
+		}
+		return c.JSON(fiber.Map{"lock": lockHolderJSON(db, &r)})
+	})
+
+	// adquiere o renueva el lease; ?force=true permite al dueño quitárselo a otro
+	api.Post("/learning-paths/:id/lock", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var r Roadmap
+		if err := db.First(&r, id).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		if !canAccess(db, claims.UserID, &r, actionEditDiagram) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden"})
+		}
+		now := time.Now()
+		expires := now.Add(lockTTL)
+		lease := map[string]interface{}{"locked_by": claims.UserID, "lock_expires_at": expires}
+		res := db.Model(&Roadmap{}).
+			Where("id = ? AND (locked_by IS NULL OR locked_by = ? OR lock_expires_at IS NULL OR lock_expires_at <= ?)", r.ID, claims.UserID, now).
+			UpdateColumns(lease)
+		if res.Error != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo bloquear"})
+		}
+		if res.RowsAffected == 0 {
+			if !c.QueryBool("force") || !canAccess(db, claims.UserID, &r, actionManage) {
+				db.First(&r, r.ID)
+				return c.Status(fiber.StatusLocked).JSON(fiber.Map{"error": "bloqueado por otro usuario", "lock": lockHolderJSON(db, &r)})
+			}
+			if err := db.Model(&Roadmap{}).Where("id = ?", r.ID).UpdateColumns(lease).Error; err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo bloquear"})
+			}
+		}
+		return c.JSON(fiber.Map{"ok": true, "lock": fiber.Map{"userId": claims.UserID, "username": claims.Username, "expiresAt": expires}, "ttlSeconds": int(lockTTL.Seconds())})
+	})
+
+	api.Post("/learning-paths/:id/unlock", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var r Roadmap
+		if err := db.First(&r, id).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		if !canAccess(db, claims.UserID, &r, actionEditDiagram) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden"})
+		}
+		// solo el titular libera su lease; el dueño puede liberar cualquiera
+		q := db.Model(&Roadmap{}).Where("id = ?", r.ID)
+		if !canAccess(db, claims.UserID, &r, actionManage) {
+			q = q.Where("(locked_by IS NULL OR locked_by = ? OR lock_expires_at <= ?)", claims.UserID, time.Now())
+		}
+		res := q.UpdateColumns(map[string]interface{}{"locked_by": nil, "lock_expires_at": nil})
+		if res.Error != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"ok": false})
+		}
+		if res.RowsAffected == 0 {
+			db.First(&r, r.ID)
+			return c.Status(fiber.StatusLocked).JSON(fiber.Map{"error": "bloqueado por otro usuario", "lock": lockHolderJSON(db, &r)})
+		}
+		return c.JSON(fiber.Map{"ok": true})
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mailer envía correos transaccionales (restablecer contraseña, verificación...)
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+var headerSanitizer = strings.NewReplacer("\r", "", "\n", "")
+
+// buildMessage arma un correo de texto plano con las cabeceras mínimas
+func buildMessage(from, to, subject, body string) []byte {
+	to = headerSanitizer.Replace(to)
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// smtpMailer envía por SMTP con PLAIN auth si hay usuario (STARTTLS lo negocia net/smtp)
+type smtpMailer struct {
+	addr     string
+	host     string
+	from     string
+	username string
+	password string
+}
+
+func (m *smtpMailer) Send(to, subject, body string) error {
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+	envelope := m.from
+	if a, err := mail.ParseAddress(m.from); err == nil {
+		envelope = a.Address
+	}
+	return smtp.SendMail(m.addr, auth, envelope, []string{to}, buildMessage(m.from, to, subject, body))
+}
+
+// outboxMailer escribe cada correo como un .eml en dir; para desarrollo local y pruebas
+type outboxMailer struct {
+	dir  string
+	from string
+	mu   sync.Mutex
+	seq  int
+}
+
+func (m *outboxMailer) Send(to, subject, body string) error {
+	m.mu.Lock()
+	m.seq++
+	name := fmt.Sprintf("%s-%03d.eml", time.Now().Format("20060102-150405.000"), m.seq)
+	m.mu.Unlock()
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path, buildMessage(m.from, to, subject, body), 0o644); err != nil {
+		return err
+	}
+	log.Printf("correo para %s guardado en %s", to, path)
+	return nil
+}
+
+// newMailerFromEnv usa SMTP si SMTP_HOST está definido y si no el outbox en MAIL_OUTBOX_DIR
+func newMailerFromEnv() Mailer {
+	from := getenv("MAIL_FROM", "Cartesia <no-reply@cartesia.local>")
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return &outboxMailer{dir: getenv("MAIL_OUTBOX_DIR", "outbox"), from: from}
+	}
+	return &smtpMailer{
+		addr:     net.JoinHostPort(host, getenv("SMTP_PORT", "587")),
+		host:     host,
+		from:     from,
+		username: os.Getenv("SMTP_USER"),
+		password: os.Getenv("SMTP_PASSWORD"),
+	}
+}
+
+// sendMailAsync envía en segundo plano: la respuesta no debe depender (ni en tiempo ni
+// en errores) de si el correo salió, para no revelar qué cuentas existen.
+func sendMailAsync(m Mailer, to, subject, body string) {
+	go func() {
+		if err := m.Send(to, subject, body); err != nil {
+			log.Printf("no se pudo enviar el correo a %s: %v", to, err)
+		}
+	}()
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// upstreamChange es un cambio del padre desde el último punto común (base) con el fork.
+// Hay conflicto cuando el fork tocó la misma celda y los mismos campos.
+type upstreamChange struct {
+	ID       string       `json:"id"`
+	Kind     string       `json:"kind"`
+	Change   string       `json:"change"`
+	Conflict bool         `json:"conflict"`
+	Summary  cellDiff     `json:"summary"`
+	Parent   []cellChange `json:"parentChanges,omitempty"`
+	Child    []cellChange `json:"childChanges,omitempty"`
+	// lo que se escribe en el fork al aplicarlo sin conflicto; nil = borrar
+	merged *diagramCell
+	// versión del padre, para resolver conflictos a su favor y avanzar la base
+	theirs *diagramCell
+	isEdge bool
+	// el fork ya tiene lo mismo que el padre: no se muestra, solo avanza la base
+	inSync bool
+}
+
+func indexCells(cells []*diagramCell) map[string]*diagramCell {
+	out := make(map[string]*diagramCell, len(cells))
+	for _, c := range cells {
+		out[c.ID] = c
+	}
+	return out
+}
+
+func sameCell(a, b *diagramCell) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return len(cellChanges(a, b)) == 0
+}
+
+// applyCellChanges copia sobre base los campos cambiados (incluidos data.*)
+func applyCellChanges(base *diagramCell, changes []cellChange) *diagramCell {
+	raw := make(map[string]json.RawMessage, len(base.Raw))
+	for k, v := range base.Raw {
+		raw[k] = v
+	}
+	var data map[string]json.RawMessage
+	_ = json.Unmarshal(raw["data"], &data)
+	dataTouched := false
+	for _, ch := range changes {
+		if key, ok := strings.CutPrefix(ch.Field, "data."); ok {
+			if data == nil {
+				data = map[string]json.RawMessage{}
+			}
+			if ch.After == nil {
+				delete(data, key)
+			} else {
+				data[key] = ch.After
+			}
+			dataTouched = true
+			continue
+		}
+		if ch.After == nil {
+			delete(raw, ch.Field)
+		} else {
+			raw[ch.Field] = ch.After
+		}
+	}
+	if dataTouched {
+		raw["data"], _ = json.Marshal(data)
+	}
+	b, _ := json.Marshal(raw)
+	cells, err := parseCells(json.RawMessage("["+string(b)+"]"), "cells", base.IsEdge)
+	if err != nil || len(cells) != 1 {
+		return base
+	}
+	return cells[0]
+}
+
+func overlappingFields(a, b []cellChange) bool {
+	fields := map[string]bool{}
+	for _, ch := range a {
+		fields[ch.Field] = true
+	}
+	for _, ch := range b {
+		if fields[ch.Field] {
+			return true
+		}
+	}
+	return false
+}
+
+// upstreamChanges compara base -> padre y base -> fork celda a celda
+func upstreamChanges(base, theirs, ours *diagramDocument) []upstreamChange {
+	var out []upstreamChange
+	for _, kind := range []string{"node", "edge"} {
+		pick := func(d *diagramDocument) []*diagramCell {
+			if kind == "edge" {
+				return d.Edges
+			}
+			return d.Nodes
+		}
+		b, t, o := indexCells(pick(base)), indexCells(pick(theirs)), indexCells(pick(ours))
+		var ids []string
+		seen := map[string]bool{}
+		for _, list := range [][]*diagramCell{pick(theirs), pick(base)} {
+			for _, c := range list {
+				if !seen[c.ID] {
+					seen[c.ID] = true
+					ids = append(ids, c.ID)
+				}
+			}
+		}
+		for _, id := range ids {
+			bc, tc, oc := b[id], t[id], o[id]
+			if sameCell(bc, tc) {
+				continue
+			}
+			ch := upstreamChange{ID: id, Kind: kind, theirs: tc, isEdge: kind == "edge", merged: tc}
+			switch {
+			case bc == nil:
+				ch.Change = "added"
+			case tc == nil:
+				ch.Change = "removed"
+			default:
+				ch.Change = "modified"
+				ch.Parent = cellChanges(bc, tc)
+			}
+			if tc != nil {
+				ch.Summary = summarizeCell(tc)
+			} else {
+				ch.Summary = summarizeCell(bc)
+			}
+			switch {
+			case sameCell(tc, oc):
+				ch.inSync = true
+			case sameCell(bc, oc):
+				// el fork no tocó la celda
+			case bc != nil && oc != nil && tc != nil:
+				ch.Child = cellChanges(bc, oc)
+				if overlappingFields(ch.Parent, ch.Child) {
+					ch.Conflict = true
+				} else {
+					ch.merged = applyCellChanges(oc, ch.Parent)
+				}
+			default:
+				// añadida en ambos lados con distinto contenido, o borrada en uno y editada en el otro
+				ch.Conflict = true
+				if bc != nil && oc != nil {
+					ch.Child = cellChanges(bc, oc)
+				}
+			}
+			out = append(out, ch)
+		}
+	}
+	return out
+}
+
+// setCell reemplaza la celda con ese id (o la añade); c nil la borra
+func setCell(cells []*diagramCell, id string, c *diagramCell) []*diagramCell {
+	for i, cur := range cells {
+		if cur.ID == id {
+			if c == nil {
+				return append(cells[:i:i], cells[i+1:]...)
+			}
+			cells[i] = c
+			return cells
+		}
+	}
+	if c != nil {
+		cells = append(cells, c)
+	}
+	return cells
+}
+
+func (d *diagramDocument) set(isEdge bool, id string, c *diagramCell) {
+	if isEdge {
+		d.Edges = setCell(d.Edges, id, c)
+	} else {
+		d.Nodes = setCell(d.Nodes, id, c)
+	}
+}
+
+// upstreamState carga el fork, su vínculo y los tres diagramas
+func upstreamState(db *gorm.DB, child *Roadmap) (*RoadmapBranch, *Roadmap, [3]*diagramDocument, error) {
+	var docs [3]*diagramDocument
+	var br RoadmapBranch
+	if err := db.Where("child_roadmap_id = ?", child.ID).First(&br).Error; err != nil {
+		return nil, nil, docs, err
+	}
+	var parent Roadmap
+	if err := db.First(&parent, br.ParentRoadmapID).Error; err != nil {
+		return nil, nil, docs, err
+	}
+	var err error
+	for i, raw := range []DiagramJSON{br.BaseJSON, parent.JSONData, child.JSONData} {
+		if docs[i], err = raw.Document(); err != nil {
+			return nil, nil, docs, err
+		}
+	}
+	return &br, &parent, docs, nil
+}
+
+func registerMergeRoutes(api fiber.Router, db *gorm.DB, jwtSecret string) {
+	load := func(c *fiber.Ctx, claims *tokenClaims, action roadmapAction) (*Roadmap, *RoadmapBranch, *Roadmap, [3]*diagramDocument, error) {
+		var docs [3]*diagramDocument
+		id, err := paramID(c, "id")
+		if err != nil {
+			return nil, nil, nil, docs, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var child Roadmap
+		if err := db.First(&child, id).Error; err != nil {
+			return nil, nil, nil, docs, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		if !canAccess(db, claimsUserID(claims), &child, action) {
+			return nil, nil, nil, docs, denyAccess(c, claims)
+		}
+		br, parent, docs, err := upstreamState(db, &child)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, nil, docs, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "el roadmap no es un fork"})
+		}
+		if err != nil {
+			return nil, nil, nil, docs, c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "diagrama ilegible", "detail": err.Error()})
+		}
+		// el padre pudo pasar a privado después del fork
+		if !canAccess(db, claimsUserID(claims), parent, actionRead) {
+			return nil, nil, nil, docs, c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden"})
+		}
+		return &child, br, parent, docs, nil
+	}
+
+	// cambios del padre pendientes de traer al fork
+	api.Get("/learning-paths/:id/upstream", func(c *fiber.Ctx) error {
+		claims, err := optionalAuth(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		child, br, parent, docs, err := load(c, claims, actionRead)
+		if child == nil {
+			return err
+		}
+		changes := []upstreamChange{}
+		conflicts := 0
+		for _, ch := range upstreamChanges(docs[0], docs[1], docs[2]) {
+			if ch.inSync {
+				continue
+			}
+			if ch.Conflict {
+				conflicts++
+			}
+			changes = append(changes, ch)
+		}
+		return c.JSON(fiber.Map{"parentId": parent.ID, "parentTitle": parent.Title, "baseRevision": br.BaseRevision,
+			"parentRevision": parent.Revision, "changes": changes, "conflicts": conflicts})
+	})
+
+	// aplica los cambios elegidos. Los conflictos necesitan resolution "parent" (quedarse
+	// con lo del padre) o "child" (conservar lo del fork); lo no elegido queda pendiente.
+	api.Post("/learning-paths/:id/upstream/merge", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		child, br, parent, docs, err := load(c, claims, actionEditDiagram)
+		if child == nil {
+			return err
+		}
+		var body struct {
+			Changes []struct {
+				ID         string `json:"id"`
+				Kind       string `json:"kind"`
+				Resolution string `json:"resolution"`
+			} `json:"changes"`
+		}
+		if err := c.BodyParser(&body); err != nil || len(body.Changes) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payload inválido"})
+		}
+		ifRev, err := parseIfMatch(c.Get(fiber.HeaderIfMatch))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "If-Match inválido"})
+		}
+		// ours sale de la revisión cargada; sin If-Match se exige esa misma para no pisar
+		// un guardado hecho mientras tanto
+		if ifRev == nil {
+			loaded := child.Revision
+			ifRev = &loaded
+		}
+		base, theirs, ours := docs[0], docs[1], docs[2]
+		// un lado vacío adopta el formato del padre
+		for _, d := range []*diagramDocument{base, ours} {
+			if len(d.Nodes)+len(d.Edges) == 0 && theirs.cellsFormat {
+				d.cellsFormat = true
+			}
+		}
+		pending := map[string]upstreamChange{}
+		for _, ch := range upstreamChanges(base, theirs, ours) {
+			if ch.inSync {
+				base.set(ch.isEdge, ch.ID, ch.theirs)
+				continue
+			}
+			pending[ch.Kind+":"+ch.ID] = ch
+		}
+		resolved := map[string]bool{}
+		for _, sel := range body.Changes {
+			kind := sel.Kind
+			if kind == "" {
+				kind = "node"
+				if _, ok := pending[kind+":"+sel.ID]; !ok {
+					kind = "edge"
+				}
+			}
+			ch, ok := pending[kind+":"+sel.ID]
+			if !ok {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "el cambio ya no está pendiente", "id": sel.ID})
+			}
+			switch {
+			case !ch.Conflict || sel.Resolution == "parent":
+				target := ch.merged
+				if ch.Conflict {
+					target = ch.theirs
+				}
+				ours.set(ch.isEdge, ch.ID, target)
+			case sel.Resolution == "child":
+			default:
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "conflicto sin resolver", "id": sel.ID})
+			}
+			base.set(ch.isEdge, ch.ID, ch.theirs)
+			resolved[kind+":"+ch.ID] = true
+		}
+		merged, err := ours.JSON()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo fusionar"})
+		}
+		if _, issues := validateDiagram(merged); len(issues) > 0 {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "diagrama inválido", "issues": issues})
+		}
+		newBase, err := base.JSON()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo fusionar"})
+		}
+		var v *RoadmapVersion
+		err = db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if v, err = saveDiagram(tx, child, diagramSave{AuthorID: claims.UserID, JSON: merged, IfRevision: ifRev}); err != nil {
+				return err
+			}
+			update := map[string]interface{}{"base_json": DiagramJSON(newBase)}
+			// la revisión base solo alcanza al padre cuando no queda nada pendiente
+			if len(resolved) == len(pending) {
+				update["base_revision"] = parent.Revision
+			}
+			return tx.Model(br).Updates(update).Error
+		})
+		switch {
+		case errors.Is(err, errDiagramLocked):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "bloqueado por otro usuario", "lock": lockHolderJSON(db, child)})
+		case errors.Is(err, errStaleRevision):
+			c.Set(fiber.HeaderETag, diagramETag(child))
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{"error": "el diagrama cambió; recarga antes de fusionar", "revision": child.Revision})
+		case err != nil:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo fusionar"})
+		}
+		c.Set(fiber.HeaderETag, diagramETag(child))
+		return c.JSON(fiber.Map{"ok": true, "versionId": v.ID, "revision": child.Revision})
+	})
+}
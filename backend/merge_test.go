@@ -0,0 +1,186 @@
+package main
+
+import "testing"
+
+const mergeBase = `{"cells":[
+	{"id":"a","shape":"rect","position":{"x":0,"y":0},"data":{"text":"A","type":"topic"}},
+	{"id":"b","shape":"rect","position":{"x":0,"y":100},"data":{"text":"B","type":"topic"}},
+	{"id":"e1","shape":"edge","source":"a","target":"b"}
+]}`
+
+func parseCell(t *testing.T, raw string) *diagramCell {
+	t.Helper()
+	doc := mustParseDiagram(t, `{"cells":[`+raw+`]}`)
+	if len(doc.Edges) == 1 {
+		return doc.Edges[0]
+	}
+	return doc.Nodes[0]
+}
+
+// cellsWith devuelve mergeBase con las celdas indicadas reemplazadas, añadidas o (con "") borradas
+func cellsWith(t *testing.T, replace map[string]string) *diagramDocument {
+	t.Helper()
+	doc := mustParseDiagram(t, mergeBase)
+	for id, raw := range replace {
+		if raw == "" {
+			doc.Nodes = setCell(doc.Nodes, id, nil)
+			doc.Edges = setCell(doc.Edges, id, nil)
+			continue
+		}
+		c := parseCell(t, raw)
+		doc.set(c.IsEdge, id, c)
+	}
+	return doc
+}
+
+func TestUpstreamChanges(t *testing.T) {
+	const (
+		aText  = `{"id":"a","shape":"rect","position":{"x":0,"y":0},"data":{"text":"A padre","type":"topic"}}`
+		aText2 = `{"id":"a","shape":"rect","position":{"x":0,"y":0},"data":{"text":"A fork","type":"topic"}}`
+		aMoved = `{"id":"a","shape":"rect","position":{"x":50,"y":0},"data":{"text":"A","type":"topic"}}`
+		aBoth  = `{"id":"a","shape":"rect","position":{"x":50,"y":0},"data":{"text":"A padre","type":"topic"}}`
+		cNode  = `{"id":"c","shape":"rect","data":{"text":"C","type":"topic"}}`
+		cNode2 = `{"id":"c","shape":"rect","data":{"text":"Otra C","type":"topic"}}`
+	)
+	tests := []struct {
+		name         string
+		theirs, ours map[string]string
+		change       string // "" = el padre no cambió nada
+		conflict     bool
+		inSync       bool
+		merged       string // celda esperada al aplicar sin conflicto; "" = borrar
+	}{
+		{
+			name: "sin cambios en el padre",
+			ours: map[string]string{"a": aText2, "c": cNode},
+		},
+		{
+			name:   "cambio del padre, fork intacto",
+			theirs: map[string]string{"a": aText},
+			change: "modified",
+			merged: aText,
+		},
+		{
+			name:   "mismo cambio en ambos lados",
+			theirs: map[string]string{"a": aText},
+			ours:   map[string]string{"a": aText},
+			change: "modified",
+			inSync: true,
+			merged: aText,
+		},
+		{
+			name:   "campos distintos se combinan",
+			theirs: map[string]string{"a": aText},
+			ours:   map[string]string{"a": aMoved},
+			change: "modified",
+			merged: aBoth,
+		},
+		{
+			name:     "mismo campo con distinto valor",
+			theirs:   map[string]string{"a": aText},
+			ours:     map[string]string{"a": aText2},
+			change:   "modified",
+			conflict: true,
+		},
+		{
+			name:   "borrado en el padre, fork intacto",
+			theirs: map[string]string{"b": ""},
+			change: "removed",
+		},
+		{
+			name:     "borrado en el padre, editado en el fork",
+			theirs:   map[string]string{"a": ""},
+			ours:     map[string]string{"a": aText2},
+			change:   "removed",
+			conflict: true,
+		},
+		{
+			name:     "editado en el padre, borrado en el fork",
+			theirs:   map[string]string{"a": aText},
+			ours:     map[string]string{"a": ""},
+			change:   "modified",
+			conflict: true,
+		},
+		{
+			name:   "añadido en el padre",
+			theirs: map[string]string{"c": cNode},
+			change: "added",
+			merged: cNode,
+		},
+		{
+			name:     "añadido en ambos con distinto contenido",
+			theirs:   map[string]string{"c": cNode},
+			ours:     map[string]string{"c": cNode2},
+			change:   "added",
+			conflict: true,
+		},
+		{
+			name:   "arista cambiada en el padre",
+			theirs: map[string]string{"e1": `{"id":"e1","shape":"edge","source":"b","target":"a"}`},
+			change: "modified",
+			merged: `{"id":"e1","shape":"edge","source":"b","target":"a"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := mustParseDiagram(t, mergeBase)
+			changes := upstreamChanges(base, cellsWith(t, tt.theirs), cellsWith(t, tt.ours))
+			if tt.change == "" {
+				if len(changes) != 0 {
+					t.Fatalf("cambios = %+v; se esperaba ninguno", changes)
+				}
+				return
+			}
+			if len(changes) != 1 {
+				t.Fatalf("cambios = %+v; se esperaba uno", changes)
+			}
+			ch := changes[0]
+			if ch.Change != tt.change || ch.Conflict != tt.conflict || ch.inSync != tt.inSync {
+				t.Fatalf("cambio = %s conflicto=%v inSync=%v; se esperaba %s conflicto=%v inSync=%v",
+					ch.Change, ch.Conflict, ch.inSync, tt.change, tt.conflict, tt.inSync)
+			}
+			if tt.conflict {
+				if len(ch.Parent) == 0 && ch.Change == "modified" {
+					t.Error("un conflicto debería mostrar los cambios del padre")
+				}
+				return
+			}
+			var want *diagramCell
+			if tt.merged != "" {
+				want = parseCell(t, tt.merged)
+			}
+			if !sameCell(ch.merged, want) {
+				t.Fatalf("resultado de aplicar = %+v; se esperaba %+v", ch.merged, want)
+			}
+		})
+	}
+}
+
+func TestUpstreamChangesApply(t *testing.T) {
+	// el padre renombra a, borra b y su arista y añade c; el fork movió a y añadió d
+	base := mustParseDiagram(t, mergeBase)
+	theirs := mustParseDiagram(t, `{"cells":[
+		{"id":"a","shape":"rect","position":{"x":0,"y":0},"data":{"text":"A padre","type":"topic"}},
+		{"id":"c","shape":"rect","data":{"text":"C","type":"topic"}}
+	]}`)
+	ours := mustParseDiagram(t, `{"cells":[
+		{"id":"a","shape":"rect","position":{"x":50,"y":0},"data":{"text":"A","type":"topic"}},
+		{"id":"b","shape":"rect","position":{"x":0,"y":100},"data":{"text":"B","type":"topic"}},
+		{"id":"d","shape":"rect","data":{"text":"D","type":"label"}},
+		{"id":"e1","shape":"edge","source":"a","target":"b"}
+	]}`)
+	for _, ch := range upstreamChanges(base, theirs, ours) {
+		if ch.Conflict {
+			t.Fatalf("conflicto inesperado en %s", ch.ID)
+		}
+		ours.set(ch.isEdge, ch.ID, ch.merged)
+	}
+	want := mustParseDiagram(t, `{"cells":[
+		{"id":"a","shape":"rect","position":{"x":50,"y":0},"data":{"text":"A padre","type":"topic"}},
+		{"id":"d","shape":"rect","data":{"text":"D","type":"label"}},
+		{"id":"c","shape":"rect","data":{"text":"C","type":"topic"}}
+	]}`)
+	if d := diffDiagrams(want, ours); len(d.Nodes.Added)+len(d.Nodes.Removed)+len(d.Nodes.Modified)+len(d.Edges.Added)+len(d.Edges.Removed)+len(d.Edges.Modified) != 0 {
+		t.Fatalf("diferencias con lo esperado: %+v", d)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// tipos de notificación; el frontend elige el icono y el enlace según el tipo
+const (
+	notifyComment  = "comment"
+	notifyRating   = "rating"
+	notifyInvite   = "invite"
+	notifyProposal = "proposal_accepted"
+	notifyFork     = "fork"
+)
+
+// Notification es la tabla notifications de db.sql más el tipo, el roadmap y quién
+// la provocó, para poder enlazarla desde la interfaz.
+type Notification struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index:idx_notification_user_read" json:"user_id"`
+	Message   string    `gorm:"type:text;not null" json:"message"`
+	Read      bool      `gorm:"not null;default:false;index:idx_notification_user_read" json:"read"`
+	Type      string    `gorm:"size:32" json:"type"`
+	RoadmapID *uint     `gorm:"index" json:"roadmap_id"`
+	ActorID   *uint     `json:"actor_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// notify avisa a cada destinatario salvo al propio actor. Un fallo aquí no debe tumbar
+// la acción que lo provoca, así que solo se registra en el log.
+func notify(db *gorm.DB, recipients []uint, actorID uint, kind string, roadmapID uint, message string) {
+	seen := map[uint]bool{actorID: true, 0: true}
+	for _, uid := range recipients {
+		if seen[uid] {
+			continue
+		}
+		seen[uid] = true
+		n := &Notification{UserID: uid, Message: message, Type: kind, ActorID: &actorID}
+		if roadmapID != 0 {
+			n.RoadmapID = &roadmapID
+		}
+		if err := db.Create(n).Error; err != nil {
+			log.Printf("no se pudo crear la notificación %s para el usuario %d: %v", kind, uid, err)
+		}
+	}
+}
+
+// roadmapOwners devuelve los dueños del roadmap, incluidos los autores que solo tienen la
+// fila de UserRoadmap (roadmaps anteriores a collaborations)
+func roadmapOwners(db *gorm.DB, roadmapID uint) []uint {
+	var ids []uint
+	ownersQuery(db, roadmapID).Scan(&ids)
+	return ids
+}
+
+// notifyOwners avisa a los dueños de r; message recibe el nombre del actor y el título
+func notifyOwners(db *gorm.DB, r *Roadmap, actorID uint, kind string, message func(actor, title string) string) {
+	notify(db, roadmapOwners(db, r.ID), actorID, kind, r.ID, message(usernamesByID(db, []uint{actorID})[actorID], r.Title))
+}
+
+func registerNotificationRoutes(api fiber.Router, db *gorm.DB, jwtSecret string) {
+	// ?unread=true para ver solo las no leídas; siempre incluye el total de no leídas
+	api.Get("/notifications", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		page, size := pageParams(c)
+		onlyUnread := c.QueryBool("unread")
+		base := func() *gorm.DB {
+			q := db.Model(&Notification{}).Where("user_id = ?", claims.UserID)
+			if onlyUnread {
+				q = q.Where("read = ?", false)
+			}
+			return q
+		}
+		var total, unread int64
+		if err := base().Count(&total).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo listar"})
+		}
+		db.Model(&Notification{}).Where("user_id = ? AND read = ?", claims.UserID, false).Count(&unread)
+		var list []Notification
+		if err := base().Order("created_at desc, id desc").Limit(size).Offset((page - 1) * size).Find(&list).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo listar"})
+		}
+		items := make([]fiber.Map, 0, len(list))
+		for _, n := range list {
+			items = append(items, fiber.Map{"id": n.ID, "type": n.Type, "message": n.Message, "read": n.Read,
+				"learningPathId": n.RoadmapID, "actorId": n.ActorID, "createdAt": n.CreatedAt})
+		}
+		return c.JSON(fiber.Map{"items": items, "page": page, "pageSize": size, "total": total, "unread": unread})
+	})
+
+	// para la insignia del navbar
+	api.Get("/notifications/unread-count", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		var count int64
+		if err := db.Model(&Notification{}).Where("user_id = ? AND read = ?", claims.UserID, false).Count(&count).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"count": 0})
+		}
+		return c.JSON(fiber.Map{"count": count})
+	})
+
+	api.Post("/notifications/read-all", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		res := db.Model(&Notification{}).Where("user_id = ? AND read = ?", claims.UserID, false).Update("read", true)
+		if res.Error != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo actualizar"})
+		}
+		return c.JSON(fiber.Map{"ok": true, "updated": res.RowsAffected})
+	})
+
+	api.Post("/notifications/:id/read", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		res := db.Model(&Notification{}).Where("id = ? AND user_id = ?", id, claims.UserID).Update("read", true)
+		if res.Error != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo actualizar"})
+		}
+		if res.RowsAffected == 0 {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		return c.JSON(fiber.Map{"ok": true})
+	})
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const passwordResetTTL = time.Hour
+
+var errResetToken = errors.New("enlace inválido o vencido")
+
+// PasswordResetToken guarda solo el hash del token enviado por correo; sirve una vez
+type PasswordResetToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// issuePasswordReset crea el token y manda el enlace si email tiene cuenta; solo vale
+// el último enlace pedido. Corre en segundo plano, así que los fallos van al log.
+func issuePasswordReset(db *gorm.DB, mailer Mailer, appURL, email string) {
+	var u User
+	if err := db.Where("lower(email) = ?", email).First(&u).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("no se pudo buscar la cuenta para restablecer la contraseña: %v", err)
+		}
+		return
+	}
+	token, err := randomToken(32)
+	if err != nil {
+		log.Printf("no se pudo generar el token de restablecimiento del usuario %d: %v", u.ID, err)
+		return
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND used_at IS NULL", u.ID).Delete(&PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&PasswordResetToken{UserID: u.ID, TokenHash: hashToken(token), ExpiresAt: time.Now().Add(passwordResetTTL)}).Error
+	})
+	if err != nil {
+		log.Printf("no se pudo guardar el token de restablecimiento del usuario %d: %v", u.ID, err)
+		return
+	}
+	link := fmt.Sprintf("%s/recuperar?token=%s", strings.TrimRight(appURL, "/"), token)
+	err = mailer.Send(u.Email, "Restablece tu contraseña de Cartesia", fmt.Sprintf(
+		"Hola %s,\n\nPara elegir una contraseña nueva abre este enlace (vence en una hora):\n\n%s\n\nSi no lo pediste, ignora este correo.\n",
+		u.Username, link))
+	if err != nil {
+		log.Printf("no se pudo enviar el correo a %s: %v", u.Email, err)
+	}
+}
+
+func registerPasswordResetRoutes(api fiber.Router, db *gorm.DB, mailer Mailer, appURL string, limiter *authLimiter) {
+	// responde igual exista o no la cuenta: la búsqueda, el token y el correo van en
+	// segundo plano para que tampoco el tiempo de respuesta delate qué cuentas existen
+	api.Post("/auth/password/forgot", func(c *fiber.Ctx) error {
+		var body struct {
+			Email string `json:"email"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payload inválido"})
+		}
+		email := normalizeEmail(body.Email)
+		if email == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "faltan campos"})
+		}
+		if wait := limiter.limit("forgot", c.IP(), email); wait > 0 {
+			return tooManyRequests(c, wait, "demasiadas solicitudes, prueba más tarde", "rate_limited")
+		}
+		go issuePasswordReset(db, mailer, appURL, email)
+		return c.JSON(fiber.Map{"ok": true, "message": "Si la cuenta existe, te enviamos un correo con instrucciones"})
+	})
+
+	// cambia la contraseña, cierra todas las sesiones abiertas y levanta el bloqueo por fallos
+	api.Post("/auth/password/reset", func(c *fiber.Ctx) error {
+		var body struct {
+			Token    string `json:"token"`
+			Password string `json:"password"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payload inválido"})
+		}
+		if body.Token == "" || body.Password == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "faltan campos"})
+		}
+		if err := validatePassword(body.Password); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(body.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo procesar"})
+		}
+		var userID uint
+		err = db.Transaction(func(tx *gorm.DB) error {
+			var rt PasswordResetToken
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashToken(body.Token), time.Now()).First(&rt).Error; err != nil {
+				return errResetToken
+			}
+			if err := tx.Model(&rt).Update("used_at", time.Now()).Error; err != nil {
+				return err
+			}
+			// el enlace llegó al buzón, así que también confirma el correo
+			if err := tx.Model(&User{}).Where("id = ?", rt.UserID).Updates(map[string]interface{}{"password_hash": string(hash), "email_verified": true}).Error; err != nil {
+				return err
+			}
+			userID = rt.UserID
+			return revokeUserSessions(tx, rt.UserID)
+		})
+		if errors.Is(err, errResetToken) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo procesar"})
+		}
+		var u User
+		if db.First(&u, userID).Error == nil {
+			limiter.reset(u.Email)
+		}
+		return c.JSON(fiber.Map{"ok": true})
+	})
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// PathStep es la proyección de un nodo del diagrama; se regenera en cada guardado
+// y conserva su id mientras el nodo siga existiendo.
+type PathStep struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	RoadmapID    uint      `gorm:"not null;uniqueIndex:idx_step_roadmap_node" json:"roadmap_id"`
+	NodeID       string    `gorm:"size:128;not null;uniqueIndex:idx_step_roadmap_node" json:"node_id"`
+	Type         string    `gorm:"size:16;not null" json:"type"`
+	Title        string    `gorm:"type:text" json:"title"`
+	Description  string    `gorm:"type:text" json:"description"`
+	Position     int       `gorm:"not null;default:0" json:"position"`
+	ParentNodeID string    `gorm:"size:128" json:"parent_node_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func pathStepJSON(s *PathStep) fiber.Map {
+	return fiber.Map{
+		"id":             s.ID,
+		"learningPathId": s.RoadmapID,
+		"nodeId":         s.NodeID,
+		"type":           s.Type,
+		"title":          s.Title,
+		"description":    s.Description,
+		"position":       s.Position,
+		"parentNodeId":   s.ParentNodeID,
+	}
+}
+
+// sectionOf devuelve la sección que contiene al nodo: la de X6 si está embebido,
+// si no la sección más pequeña cuyo rectángulo contiene el centro del nodo.
+func sectionOf(n *diagramCell, sections []*diagramCell) string {
+	for _, s := range sections {
+		if n.Parent == s.ID {
+			return s.ID
+		}
+	}
+	cx, cy := n.Position.X+n.Size.Width/2, n.Position.Y+n.Size.Height/2
+	best, bestArea := "", 0.0
+	for _, s := range sections {
+		if s.ID == n.ID {
+			continue
+		}
+		if cx < s.Position.X || cy < s.Position.Y || cx > s.Position.X+s.Size.Width || cy > s.Position.Y+s.Size.Height {
+			continue
+		}
+		if area := s.Size.Width * s.Size.Height; best == "" || area < bestArea {
+			best, bestArea = s.ID, area
+		}
+	}
+	return best
+}
+
+// syncPathSteps deja path_steps igual que los nodos de doc
+func syncPathSteps(tx *gorm.DB, roadmapID uint, doc *diagramDocument) error {
+	nodes := make([]*diagramCell, 0, len(doc.Nodes))
+	var sections []*diagramCell
+	for _, n := range doc.Nodes {
+		if n.ID == "" {
+			continue
+		}
+		nodes = append(nodes, n)
+		if n.Data.Type == "section" {
+			sections = append(sections, n)
+		}
+	}
+	// orden de lectura: de arriba abajo y de izquierda a derecha
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Position.Y != nodes[j].Position.Y {
+			return nodes[i].Position.Y < nodes[j].Position.Y
+		}
+		return nodes[i].Position.X < nodes[j].Position.X
+	})
+
+	var existing []PathStep
+	if err := tx.Where("roadmap_id = ?", roadmapID).Find(&existing).Error; err != nil {
+		return err
+	}
+	byNode := map[string]*PathStep{}
+	for i := range existing {
+		byNode[existing[i].NodeID] = &existing[i]
+	}
+	keep := map[string]bool{}
+	for i, n := range nodes {
+		keep[n.ID] = true
+		want := PathStep{
+			RoadmapID:    roadmapID,
+			NodeID:       n.ID,
+			Type:         n.Data.Type,
+			Title:        n.Label(),
+			Description:  n.Data.ContentDescription,
+			Position:     i,
+			ParentNodeID: sectionOf(n, sections),
+		}
+		cur, ok := byNode[n.ID]
+		if !ok {
+			if err := tx.Create(&want).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if cur.Type == want.Type && cur.Title == want.Title && cur.Description == want.Description &&
+			cur.Position == want.Position && cur.ParentNodeID == want.ParentNodeID {
+			continue
+		}
+		if err := tx.Model(cur).Updates(map[string]interface{}{
+			"type":           want.Type,
+			"title":          want.Title,
+			"description":    want.Description,
+			"position":       want.Position,
+			"parent_node_id": want.ParentNodeID,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	var stale []uint
+	for _, s := range existing {
+		if !keep[s.NodeID] {
+			stale = append(stale, s.ID)
+		}
+	}
+	if len(stale) > 0 {
+		return tx.Delete(&PathStep{}, stale).Error
+	}
+	return nil
+}
+
+// backfillPathSteps proyecta los diagramas guardados antes de que existiera path_steps
+func backfillPathSteps(db *gorm.DB) {
+	var list []Roadmap
+	if err := db.Where("json_data IS NOT NULL AND NOT EXISTS (SELECT 1 FROM path_steps ps WHERE ps.roadmap_id = roadmaps.id)").Find(&list).Error; err != nil {
+		log.Printf("no se pudieron proyectar los pasos: %v", err)
+		return
+	}
+	for _, r := range list {
+		doc, err := r.JSONData.Document()
+		if err != nil {
+			log.Printf("roadmap %d: diagrama ilegible, sin pasos: %v", r.ID, err)
+			continue
+		}
+		if err := db.Transaction(func(tx *gorm.DB) error { return syncPathSteps(tx, r.ID, doc) }); err != nil {
+			log.Printf("roadmap %d: no se pudieron proyectar los pasos: %v", r.ID, err)
+		}
+	}
+}
+
+func registerPathStepRoutes(api fiber.Router, db *gorm.DB, jwtSecret string) {
+	api.Get("/learning-paths/:id/steps", func(c *fiber.Ctx) error {
+		claims, err := optionalAuth(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var r Roadmap
+		if err := db.First(&r, id).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"items": []fiber.Map{}})
+		}
+		if !canAccess(db, claimsUserID(claims), &r, actionRead) {
+			return denyAccess(c, claims)
+		}
+		var steps []PathStep
+		q := db.Where("roadmap_id = ?", r.ID)
+		if t := c.Query("type"); t != "" {
+			q = q.Where("type = ?", t)
+		}
+		if err := q.Order("position asc").Find(&steps).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"items": []fiber.Map{}})
+		}
+		items := make([]fiber.Map, 0, len(steps))
+		for i := range steps {
+			items = append(items, pathStepJSON(&steps[i]))
+		}
+		return c.JSON(fiber.Map{"items": items})
+	})
+
+	api.Get("/path-steps/:id", func(c *fiber.Ctx) error {
+		claims, err := optionalAuth(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var s PathStep
+		if err := db.First(&s, id).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		var r Roadmap
+		if err := db.First(&r, s.RoadmapID).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		if !canAccess(db, claimsUserID(claims), &r, actionRead) {
+			return denyAccess(c, claims)
+		}
+		return c.JSON(pathStepJSON(&s))
+	})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type roadmapAction string
+
+const (
+	actionRead        roadmapAction = "read"         // ver roadmaps privados y su diagrama
+	actionPropose     roadmapAction = "propose"      // proponer cambios sin editar
+	actionEditDiagram roadmapAction = "edit_diagram" // guardar el diagrama
+	actionEditMeta    roadmapAction = "edit_meta"    // título y descripción
+	actionManage      roadmapAction = "manage"       // borrar, visibilidad y colaboradores
+)
+
+var rolePermissions = map[string][]roadmapAction{
+	roleOwner:       {actionRead, actionPropose, actionEditDiagram, actionEditMeta, actionManage},
+	roleEditor:      {actionRead, actionPropose, actionEditDiagram, actionEditMeta},
+	roleContributor: {actionRead, actionPropose},
+	roleViewer:      {actionRead},
+}
+
+func roleCan(role string, action roadmapAction) bool {
+	for _, a := range rolePermissions[role] {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// canAccess decide si el usuario (0 = anónimo) puede hacer action sobre el roadmap.
+// En un roadmap público cualquiera lo lee y cualquier usuario con sesión puede proponer
+// cambios; aceptarlos sigue siendo cosa de editores y dueños.
+func canAccess(db *gorm.DB, userID uint, r *Roadmap, action roadmapAction) bool {
+	if action == actionRead && r.Visibility == "public" {
+		return true
+	}
+	if userID == 0 {
+		return false
+	}
+	if action == actionPropose && r.Visibility == "public" {
+		return true
+	}
+	return roleCan(roadmapRole(db, userID, r.ID), action)
+}
+
+// optionalAuth devuelve nil sin error cuando la petición no trae token
+func optionalAuth(c *fiber.Ctx, db *gorm.DB, secret string) (*tokenClaims, error) {
+	if c.Get("Authorization") == "" {
+		return nil, nil
+	}
+	return authenticate(c, db, secret)
+}
+
+func claimsUserID(claims *tokenClaims) uint {
+	if claims == nil {
+		return 0
+	}
+	return claims.UserID
+}
+
+// denyAccess responde 401 a anónimos y 403 a usuarios sin permiso
+func denyAccess(c *fiber.Ctx, claims *tokenClaims) error {
+	if claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "no autorizado"})
+	}
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden"})
+}
+
+// scopeVisibleTo limita una consulta sobre roadmaps a los que el usuario puede leer:
+// públicos más aquellos en los que tiene algún rol (0 = anónimo, solo públicos)
+func scopeVisibleTo(userID uint) func(*gorm.DB) *gorm.DB {
+	return func(q *gorm.DB) *gorm.DB {
+		if userID == 0 {
+			return q.Where("roadmaps.visibility = ?", "public")
+		}
+		return q.Where(`(roadmaps.visibility = ?
+			OR EXISTS (SELECT 1 FROM collaborations cb WHERE cb.roadmap_id = roadmaps.id AND cb.collaborator_id = ?)
+			OR EXISTS (SELECT 1 FROM user_roadmaps ur WHERE ur.roadmap_id = roadmaps.id AND ur.user_id = ?))`, "public", userID, userID)
+	}
+}
+
+// scopeOwnedBy: roadmaps de los que userID es owner, con la misma regla que roadmapRole:
+// manda la colaboración y, si no hay, la fila de UserRoadmap del autor
+func scopeOwnedBy(userID uint) func(*gorm.DB) *gorm.DB {
+	return func(q *gorm.DB) *gorm.DB {
+		return q.Where(`(EXISTS (SELECT 1 FROM collaborations cb WHERE cb.roadmap_id = roadmaps.id AND cb.collaborator_id = ? AND cb.role = ?)
+			OR (EXISTS (SELECT 1 FROM user_roadmaps ur WHERE ur.roadmap_id = roadmaps.id AND ur.user_id = ?)
+				AND NOT EXISTS (SELECT 1 FROM collaborations cb WHERE cb.roadmap_id = roadmaps.id AND cb.collaborator_id = ?)))`, userID, roleOwner, userID, userID)
+	}
+}
+
+// scopeSharedWith: roadmaps en los que userID colabora con cualquier otro rol (también
+// el autor al que le bajaron el rol)
+func scopeSharedWith(userID uint) func(*gorm.DB) *gorm.DB {
+	return func(q *gorm.DB) *gorm.DB {
+		return q.Where(`EXISTS (SELECT 1 FROM collaborations cb WHERE cb.roadmap_id = roadmaps.id AND cb.collaborator_id = ? AND cb.role <> ?)`, userID, roleOwner)
+	}
+}
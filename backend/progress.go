@@ -0,0 +1,294 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	progressNotStarted = "not_started"
+	progressInProgress = "in_progress"
+	progressCompleted  = "completed"
+	progressPaused     = "paused"
+)
+
+var errProgressState = errors.New("transición de estado no permitida")
+
+// UserProgress es el estado de un alumno en un roadmap (tabla user_progress de db.sql)
+type UserProgress struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"not null;uniqueIndex:idx_progress_user_roadmap" json:"user_id"`
+	RoadmapID   uint       `gorm:"not null;uniqueIndex:idx_progress_user_roadmap;index" json:"roadmap_id"`
+	State       string     `gorm:"column:progress_state;size:16;not null;default:not_started" json:"state"`
+	StartedAt   *time.Time `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName usa el nombre de db.sql en lugar del plural que pondría GORM
+func (UserProgress) TableName() string {
+	return "user_progress"
+}
+
+// NodeProgress marca un nodo del diagrama como hecho. Va por node id, como los
+// comentarios de paso, para no perderse si el paso se regenera.
+type NodeProgress struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"not null;uniqueIndex:idx_node_progress" json:"user_id"`
+	RoadmapID   uint      `gorm:"not null;uniqueIndex:idx_node_progress;index" json:"roadmap_id"`
+	NodeID      string    `gorm:"size:128;not null;uniqueIndex:idx_node_progress" json:"node_id"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// progressStepTypes son los nodos que cuentan para el porcentaje
+var progressStepTypes = []string{"topic", "subtopic"}
+
+// allowedProgress: a qué estados se puede pasar desde cada uno
+var allowedProgress = map[string]map[string]bool{
+	progressNotStarted: {progressInProgress: true, progressCompleted: true},
+	progressInProgress: {progressPaused: true, progressCompleted: true},
+	progressPaused:     {progressInProgress: true, progressCompleted: true},
+	progressCompleted:  {progressInProgress: true},
+}
+
+type progressCounts struct {
+	Done  int64
+	Total int64
+}
+
+func (p progressCounts) percent(state string) int {
+	if p.Total == 0 {
+		if state == progressCompleted {
+			return 100
+		}
+		return 0
+	}
+	return int(p.Done * 100 / p.Total)
+}
+
+// countProgress cuenta pasos hechos/totales de userID en cada roadmap; solo los
+// nodos que siguen en el diagrama cuentan como hechos.
+func countProgress(db *gorm.DB, userID uint, roadmapIDs []uint) map[uint]progressCounts {
+	out := make(map[uint]progressCounts, len(roadmapIDs))
+	if len(roadmapIDs) == 0 {
+		return out
+	}
+	var rows []struct {
+		RoadmapID uint
+		Done      int64
+		Total     int64
+	}
+	db.Raw(`SELECT ps.roadmap_id, COUNT(np.id) AS done, COUNT(*) AS total
+		FROM path_steps ps
+		LEFT JOIN node_progresses np ON np.roadmap_id = ps.roadmap_id AND np.node_id = ps.node_id AND np.user_id = ?
+		WHERE ps.roadmap_id IN ? AND ps.type IN ?
+		GROUP BY ps.roadmap_id`, userID, roadmapIDs, progressStepTypes).Scan(&rows)
+	for _, r := range rows {
+		out[r.RoadmapID] = progressCounts{Done: r.Done, Total: r.Total}
+	}
+	return out
+}
+
+// moveProgress cambia el estado respetando allowedProgress; crea la fila si no existe
+func moveProgress(tx *gorm.DB, userID, roadmapID uint, to string) (*UserProgress, error) {
+	p := UserProgress{UserID: userID, RoadmapID: roadmapID, State: progressNotStarted}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ? AND roadmap_id = ?", userID, roadmapID).FirstOrCreate(&p).Error; err != nil {
+		return nil, err
+	}
+	if p.State == to {
+		return &p, nil
+	}
+	if !allowedProgress[p.State][to] {
+		return nil, errProgressState
+	}
+	now := time.Now()
+	updates := map[string]interface{}{"progress_state": to}
+	if p.StartedAt == nil {
+		updates["started_at"] = now
+	}
+	if to == progressCompleted {
+		updates["completed_at"] = now
+	} else {
+		updates["completed_at"] = nil
+	}
+	if err := tx.Model(&p).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return &p, tx.First(&p, p.ID).Error
+}
+
+func progressJSON(r *Roadmap, p *UserProgress, counts progressCounts, nodes []string) fiber.Map {
+	state := progressNotStarted
+	var startedAt, completedAt *time.Time
+	if p != nil {
+		state, startedAt, completedAt = p.State, p.StartedAt, p.CompletedAt
+	}
+	out := fiber.Map{
+		"learningPathId": r.ID,
+		"title":          r.Title,
+		"state":          state,
+		"percent":        counts.percent(state),
+		"completedSteps": counts.Done,
+		"totalSteps":     counts.Total,
+		"startedAt":      startedAt,
+		"completedAt":    completedAt,
+	}
+	if nodes != nil {
+		out["completedNodes"] = nodes
+	}
+	return out
+}
+
+func registerProgressRoutes(api fiber.Router, db *gorm.DB, jwtSecret string) {
+	// loadRoadmap autentica y comprueba que el alumno puede leer el roadmap
+	loadRoadmap := func(c *fiber.Ctx) (*tokenClaims, *Roadmap, error) {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return nil, nil, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return nil, nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var r Roadmap
+		if err := db.First(&r, id).Error; err != nil {
+			return nil, nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		if !canAccess(db, claims.UserID, &r, actionRead) {
+			return nil, nil, denyAccess(c, claims)
+		}
+		return claims, &r, nil
+	}
+
+	current := func(c *fiber.Ctx, userID uint, r *Roadmap) error {
+		var p UserProgress
+		found := db.Where("user_id = ? AND roadmap_id = ?", userID, r.ID).First(&p).Error == nil
+		var nodes []string
+		if err := db.Model(&NodeProgress{}).Where("user_id = ? AND roadmap_id = ?", userID, r.ID).Order("completed_at").Pluck("node_id", &nodes).Error; err != nil || nodes == nil {
+			nodes = []string{}
+		}
+		counts := countProgress(db, userID, []uint{r.ID})[r.ID]
+		if !found {
+			return c.JSON(progressJSON(r, nil, counts, nodes))
+		}
+		return c.JSON(progressJSON(r, &p, counts, nodes))
+	}
+
+	api.Get("/learning-paths/:id/progress", func(c *fiber.Ctx) error {
+		claims, r, err := loadRoadmap(c)
+		if r == nil {
+			return err
+		}
+		return current(c, claims.UserID, r)
+	})
+
+	for action, to := range map[string]string{"start": progressInProgress, "pause": progressPaused, "complete": progressCompleted} {
+		to := to
+		api.Post("/learning-paths/:id/progress/"+action, func(c *fiber.Ctx) error {
+			claims, r, err := loadRoadmap(c)
+			if r == nil {
+				return err
+			}
+			err = db.Transaction(func(tx *gorm.DB) error {
+				_, err := moveProgress(tx, claims.UserID, r.ID, to)
+				return err
+			})
+			if errors.Is(err, errProgressState) {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+			}
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo actualizar"})
+			}
+			return current(c, claims.UserID, r)
+		})
+	}
+
+	// marcar o desmarcar un nodo; al marcar se empieza el roadmap si no estaba empezado
+	// y al llegar al 100% se da por completado
+	api.Put("/learning-paths/:id/progress/nodes/:nodeId", func(c *fiber.Ctx) error {
+		claims, r, err := loadRoadmap(c)
+		if r == nil {
+			return err
+		}
+		var body struct {
+			Done *bool `json:"done"`
+		}
+		if err := c.BodyParser(&body); err != nil || body.Done == nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payload inválido"})
+		}
+		nodeID := c.Params("nodeId")
+		var step PathStep
+		if err := db.Where("roadmap_id = ? AND node_id = ? AND type IN ?", r.ID, nodeID, progressStepTypes).First(&step).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if !*body.Done {
+				return tx.Where("user_id = ? AND roadmap_id = ? AND node_id = ?", claims.UserID, r.ID, nodeID).Delete(&NodeProgress{}).Error
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&NodeProgress{UserID: claims.UserID, RoadmapID: r.ID, NodeID: nodeID, CompletedAt: time.Now()}).Error; err != nil {
+				return err
+			}
+			var p UserProgress
+			state := progressNotStarted
+			if tx.Where("user_id = ? AND roadmap_id = ?", claims.UserID, r.ID).First(&p).Error == nil {
+				state = p.State
+			}
+			if state == progressCompleted {
+				return nil
+			}
+			if state != progressInProgress {
+				if _, err := moveProgress(tx, claims.UserID, r.ID, progressInProgress); err != nil {
+					return err
+				}
+			}
+			if counts := countProgress(tx, claims.UserID, []uint{r.ID})[r.ID]; counts.Total > 0 && counts.Done == counts.Total {
+				_, err := moveProgress(tx, claims.UserID, r.ID, progressCompleted)
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo actualizar"})
+		}
+		return current(c, claims.UserID, r)
+	})
+
+	// progreso del usuario en todos los roadmaps que ha empezado
+	api.Get("/me/progress", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		var list []UserProgress
+		if err := db.Where("user_id = ?", claims.UserID).Order("updated_at desc").Find(&list).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"items": []fiber.Map{}})
+		}
+		ids := make([]uint, 0, len(list))
+		for _, p := range list {
+			ids = append(ids, p.RoadmapID)
+		}
+		var roadmaps []Roadmap
+		if len(ids) > 0 {
+			db.Scopes(scopeVisibleTo(claims.UserID)).Select("id, title, visibility").Where("roadmaps.id IN ?", ids).Find(&roadmaps)
+		}
+		byID := map[uint]*Roadmap{}
+		for i := range roadmaps {
+			byID[roadmaps[i].ID] = &roadmaps[i]
+		}
+		counts := countProgress(db, claims.UserID, ids)
+		items := make([]fiber.Map, 0, len(list))
+		for i := range list {
+			r, ok := byID[list[i].RoadmapID]
+			if !ok {
+				continue
+			}
+			items = append(items, progressJSON(r, &list[i], counts[r.ID], nil))
+		}
+		return c.JSON(fiber.Map{"items": items})
+	})
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	proposalOpen      = "open"
+	proposalAccepted  = "accepted"
+	proposalRejected  = "rejected"
+	proposalWithdrawn = "withdrawn"
+)
+
+// ChangeProposal es un diagrama propuesto por un colaborador (rol contributor o
+// superior) contra la revisión BaseRevision del roadmap. Al aceptarla se guarda
+// como una versión más con saveDiagram.
+type ChangeProposal struct {
+	ID           uint        `gorm:"primaryKey" json:"id"`
+	RoadmapID    uint        `gorm:"not null;index" json:"roadmap_id"`
+	AuthorID     uint        `gorm:"not null;index" json:"author_id"`
+	Title        string      `gorm:"size:200;not null" json:"title"`
+	Description  string      `gorm:"type:text" json:"description"`
+	JSONData     DiagramJSON `gorm:"type:jsonb" json:"-"`
+	BaseRevision uint        `gorm:"not null;default:0" json:"base_revision"`
+	State        string      `gorm:"size:16;not null;default:open;index" json:"state"`
+	ReviewerID   *uint       `json:"reviewer_id"`
+	ReviewedAt   *time.Time  `json:"reviewed_at"`
+	VersionID    *uint       `json:"version_id"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+}
+
+type ProposalComment struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ProposalID uint      `gorm:"not null;index" json:"proposal_id"`
+	UserID     uint      `gorm:"not null" json:"user_id"`
+	Content    string    `gorm:"type:text;not null" json:"content"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+var errProposalClosed = errors.New("la propuesta ya está cerrada")
+
+// usernamesByID resuelve los nombres de usuario de una lista de ids
+func usernamesByID(db *gorm.DB, ids []uint) map[uint]string {
+	out := map[uint]string{}
+	if len(ids) == 0 {
+		return out
+	}
+	var users []User
+	db.Select("id, username").Where("id IN ?", ids).Find(&users)
+	for _, u := range users {
+		out[u.ID] = u.Username
+	}
+	return out
+}
+
+func proposalJSON(p *ChangeProposal, uname map[uint]string) fiber.Map {
+	return fiber.Map{"id": p.ID, "learningPathId": p.RoadmapID, "title": p.Title, "description": p.Description, "state": p.State,
+		"authorId": p.AuthorID, "authorUsername": uname[p.AuthorID], "baseRevision": p.BaseRevision, "reviewerId": p.ReviewerID,
+		"reviewedAt": p.ReviewedAt, "versionId": p.VersionID, "createdAt": p.CreatedAt, "updatedAt": p.UpdatedAt}
+}
+
+// closeProposal pasa una propuesta abierta a state; falla si otro la cerró antes
+func closeProposal(tx *gorm.DB, p *ChangeProposal, state string, reviewerID uint, versionID *uint) error {
+	now := time.Now()
+	res := tx.Model(&ChangeProposal{}).Where("id = ? AND state = ?", p.ID, proposalOpen).
+		Updates(map[string]interface{}{"state": state, "reviewer_id": reviewerID, "reviewed_at": now, "version_id": versionID})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errProposalClosed
+	}
+	return tx.First(p, p.ID).Error
+}
+
+func registerProposalRoutes(api fiber.Router, db *gorm.DB, jwtSecret string) {
+	// loadProposal carga la propuesta y su roadmap. La ven su autor y quien tenga rol de
+	// proponer en el roadmap (contributor, editor, owner); en un roadmap público cualquiera
+	// puede proponer, pero no por eso ve las propuestas de los demás.
+	loadProposal := func(c *fiber.Ctx, claims *tokenClaims) (*ChangeProposal, *Roadmap, error) {
+		id, err := paramID(c, "id")
+		if err != nil {
+			return nil, nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var p ChangeProposal
+		if err := db.First(&p, id).Error; err != nil {
+			return nil, nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		var r Roadmap
+		if err := db.First(&r, p.RoadmapID).Error; err != nil {
+			return nil, nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		if p.AuthorID != claims.UserID && !roleCan(roadmapRole(db, claims.UserID, r.ID), actionPropose) {
+			return nil, nil, c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden"})
+		}
+		return &p, &r, nil
+	}
+
+	api.Post("/learning-paths/:id/proposals", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var r Roadmap
+		if err := db.First(&r, id).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		if !canAccess(db, claims.UserID, &r, actionPropose) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden"})
+		}
+		var body struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			DiagramJSON string `json:"diagramJSON"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payload inválido"})
+		}
+		title := strings.TrimSpace(body.Title)
+		if title == "" || strings.TrimSpace(body.DiagramJSON) == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "faltan campos"})
+		}
+		if _, issues := validateDiagram(body.DiagramJSON); len(issues) > 0 {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "diagrama inválido", "issues": issues})
+		}
+		p := &ChangeProposal{RoadmapID: r.ID, AuthorID: claims.UserID, Title: title, Description: strings.TrimSpace(body.Description),
+			JSONData: DiagramJSON(body.DiagramJSON), BaseRevision: r.Revision, State: proposalOpen}
+		if err := db.Create(p).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo crear la propuesta"})
+		}
+		return c.JSON(fiber.Map{"id": p.ID, "state": p.State, "baseRevision": p.BaseRevision})
+	})
+
+	// ?state=open|accepted|rejected|withdrawn; sin filtro, todas, las más recientes primero
+	api.Get("/learning-paths/:id/proposals", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var r Roadmap
+		if err := db.First(&r, id).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"items": []fiber.Map{}})
+		}
+		q := db.Where("roadmap_id = ?", r.ID)
+		// sin rol en el roadmap solo se ven las propias
+		if !roleCan(roadmapRole(db, claims.UserID, r.ID), actionPropose) {
+			q = q.Where("author_id = ?", claims.UserID)
+		}
+		switch state := c.Query("state"); state {
+		case "":
+		case proposalOpen, proposalAccepted, proposalRejected, proposalWithdrawn:
+			q = q.Where("state = ?", state)
+		default:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "state inválido"})
+		}
+		var list []ChangeProposal
+		if err := q.Omit("json_data").Order("id desc").Find(&list).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"items": []fiber.Map{}})
+		}
+		ids := make([]uint, 0, len(list))
+		userIDs := make([]uint, 0, len(list))
+		for _, p := range list {
+			ids = append(ids, p.ID)
+			userIDs = append(userIDs, p.AuthorID)
+		}
+		var counts []struct {
+			ProposalID uint
+			Count      int64
+		}
+		if len(ids) > 0 {
+			db.Model(&ProposalComment{}).Select("proposal_id, COUNT(*) AS count").Where("proposal_id IN ?", ids).Group("proposal_id").Scan(&counts)
+		}
+		byID := map[uint]int64{}
+		for _, n := range counts {
+			byID[n.ProposalID] = n.Count
+		}
+		uname := usernamesByID(db, userIDs)
+		items := make([]fiber.Map, 0, len(list))
+		for i := range list {
+			item := proposalJSON(&list[i], uname)
+			item["commentsCount"] = byID[list[i].ID]
+			items = append(items, item)
+		}
+		return c.JSON(fiber.Map{"items": items})
+	})
+
+	// detalle con el diff contra el diagrama actual y la conversación de revisión
+	api.Get("/proposals/:id", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		p, r, err := loadProposal(c, claims)
+		if p == nil {
+			return err
+		}
+		current, err := r.JSONData.Document()
+		if err != nil {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "diagrama ilegible", "detail": err.Error()})
+		}
+		proposed, err := p.JSONData.Document()
+		if err != nil {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "diagrama ilegible", "detail": err.Error()})
+		}
+		var comments []ProposalComment
+		db.Where("proposal_id = ?", p.ID).Order("created_at asc").Find(&comments)
+		userIDs := []uint{p.AuthorID}
+		if p.ReviewerID != nil {
+			userIDs = append(userIDs, *p.ReviewerID)
+		}
+		for _, cm := range comments {
+			userIDs = append(userIDs, cm.UserID)
+		}
+		uname := usernamesByID(db, userIDs)
+		list := make([]fiber.Map, 0, len(comments))
+		for _, cm := range comments {
+			list = append(list, fiber.Map{"id": cm.ID, "content": cm.Content, "createdAt": cm.CreatedAt, "userId": cm.UserID, "username": uname[cm.UserID]})
+		}
+		diff := diffDiagrams(current, proposed)
+		out := proposalJSON(p, uname)
+		out["diagramJSON"] = p.JSONData
+		out["currentRevision"] = r.Revision
+		out["outdated"] = p.BaseRevision != r.Revision
+		out["diff"] = fiber.Map{"nodes": diff.Nodes, "edges": diff.Edges}
+		out["comments"] = list
+		out["canReview"] = canAccess(db, claims.UserID, r, actionEditDiagram)
+		return c.JSON(out)
+	})
+
+	// el autor puede actualizar una propuesta abierta; queda basada en la revisión actual
+	api.Put("/proposals/:id", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		p, r, err := loadProposal(c, claims)
+		if p == nil {
+			return err
+		}
+		if p.AuthorID != claims.UserID {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden"})
+		}
+		if p.State != proposalOpen {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": errProposalClosed.Error()})
+		}
+		var body struct {
+			Title       *string `json:"title"`
+			Description *string `json:"description"`
+			DiagramJSON *string `json:"diagramJSON"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payload inválido"})
+		}
+		updates := map[string]interface{}{}
+		if body.Title != nil {
+			if strings.TrimSpace(*body.Title) == "" {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "faltan campos"})
+			}
+			updates["title"] = strings.TrimSpace(*body.Title)
+		}
+		if body.Description != nil {
+			updates["description"] = strings.TrimSpace(*body.Description)
+		}
+		if body.DiagramJSON != nil {
+			if _, issues := validateDiagram(*body.DiagramJSON); len(issues) > 0 {
+				return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "diagrama inválido", "issues": issues})
+			}
+			updates["json_data"] = DiagramJSON(*body.DiagramJSON)
+			updates["base_revision"] = r.Revision
+		}
+		if len(updates) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payload inválido"})
+		}
+		res := db.Model(&ChangeProposal{}).Where("id = ? AND state = ?", p.ID, proposalOpen).Updates(updates)
+		if res.Error != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo actualizar"})
+		}
+		if res.RowsAffected == 0 {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": errProposalClosed.Error()})
+		}
+		return c.JSON(fiber.Map{"ok": true})
+	})
+
+	api.Post("/proposals/:id/comments", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		p, _, err := loadProposal(c, claims)
+		if p == nil {
+			return err
+		}
+		if !emailVerified(db, claims.UserID) {
+			return denyUnverified(c)
+		}
+		var body struct {
+			Content string `json:"content"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payload inválido"})
+		}
+		content := strings.TrimSpace(body.Content)
+		if content == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "contenido vacío"})
+		}
+		cm := &ProposalComment{ProposalID: p.ID, UserID: claims.UserID, Content: content}
+		if err := db.Create(cm).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo comentar"})
+		}
+		return c.JSON(fiber.Map{"id": cm.ID})
+	})
+
+	// aceptar guarda el diagrama propuesto como nueva versión. Si el roadmap cambió
+	// desde la base de la propuesta responde 409, salvo {"force": true}.
+	api.Post("/proposals/:id/accept", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		p, r, err := loadProposal(c, claims)
+		if p == nil {
+			return err
+		}
+		if !canAccess(db, claims.UserID, r, actionEditDiagram) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden"})
+		}
+		if p.State != proposalOpen {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": errProposalClosed.Error()})
+		}
+		var body struct {
+			Force bool `json:"force"`
+		}
+		_ = c.BodyParser(&body)
+		// las reglas de validación pueden haber cambiado desde que se creó
+		if _, issues := validateDiagram(string(p.JSONData)); len(issues) > 0 {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "diagrama inválido", "issues": issues})
+		}
+		save := diagramSave{AuthorID: claims.UserID, JSON: string(p.JSONData)}
+		if !body.Force {
+			save.IfRevision = &p.BaseRevision
+		}
+		var v *RoadmapVersion
+		err = db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if v, err = saveDiagram(tx, r, save); err != nil {
+				return err
+			}
+			return closeProposal(tx, p, proposalAccepted, claims.UserID, &v.ID)
+		})
+		switch {
+		case errors.Is(err, errDiagramLocked):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "bloqueado por otro usuario", "lock": lockHolderJSON(db, r)})
+		case errors.Is(err, errStaleRevision):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "el roadmap cambió desde que se hizo la propuesta", "baseRevision": p.BaseRevision, "revision": r.Revision})
+		case errors.Is(err, errProposalClosed):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		case err != nil:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo aceptar"})
+		}
+		notify(db, []uint{p.AuthorID}, claims.UserID, notifyProposal, r.ID, fmt.Sprintf("Tu propuesta «%s» en «%s» fue aceptada", p.Title, r.Title))
+		c.Set(fiber.HeaderETag, diagramETag(r))
+		return c.JSON(fiber.Map{"ok": true, "versionId": v.ID, "revision": r.Revision})
+	})
+
+	// rechazar (revisores) o retirar (autor); el motivo opcional queda como comentario
+	for action, state := range map[string]string{"reject": proposalRejected, "withdraw": proposalWithdrawn} {
+		state := state
+		api.Post("/proposals/:id/"+action, func(c *fiber.Ctx) error {
+			claims, err := authenticate(c, db, jwtSecret)
+			if err != nil {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+			}
+			p, r, err := loadProposal(c, claims)
+			if p == nil {
+				return err
+			}
+			allowed := p.AuthorID == claims.UserID
+			if state == proposalRejected {
+				allowed = canAccess(db, claims.UserID, r, actionEditDiagram)
+			}
+			if !allowed {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden"})
+			}
+			var body struct {
+				Reason string `json:"reason"`
+			}
+			_ = c.BodyParser(&body)
+			err = db.Transaction(func(tx *gorm.DB) error {
+				if err := closeProposal(tx, p, state, claims.UserID, nil); err != nil {
+					return err
+				}
+				if reason := strings.TrimSpace(body.Reason); reason != "" {
+					return tx.Create(&ProposalComment{ProposalID: p.ID, UserID: claims.UserID, Content: reason}).Error
+				}
+				return nil
+			})
+			if errors.Is(err, errProposalClosed) {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+			}
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo actualizar"})
+			}
+			return c.JSON(fiber.Map{"ok": true, "state": p.State})
+		})
+	}
+}
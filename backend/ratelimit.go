@@ -0,0 +1,312 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// tras este tiempo sin bloqueos el siguiente vuelve a durar lockoutBase
+	lockoutDecay = 24 * time.Hour
+	unlockTTL    = 24 * time.Hour
+)
+
+// rateRule es un límite de ventana deslizante; Limit 0 lo desactiva
+type rateRule struct {
+	Limit  int
+	Window time.Duration
+}
+
+// parseRateRule lee "N/duración" (p. ej. "20/5m"); "off" o "0" desactivan el límite
+func parseRateRule(v string, def rateRule) rateRule {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return def
+	}
+	if v == "off" || v == "0" {
+		return rateRule{}
+	}
+	n, w, ok := strings.Cut(v, "/")
+	limit, err1 := strconv.Atoi(n)
+	window, err2 := time.ParseDuration(w)
+	if !ok || err1 != nil || err2 != nil || limit < 0 || window <= 0 {
+		log.Printf("límite %q inválido, se usa %d/%s", v, def.Limit, def.Window)
+		return def
+	}
+	return rateRule{Limit: limit, Window: window}
+}
+
+func getenvDuration(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("%s=%q inválido, se usa %s", key, v, def)
+	}
+	return def
+}
+
+func getenvInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			return n
+		}
+		log.Printf("%s=%q inválido, se usa %d", key, v, def)
+	}
+	return def
+}
+
+// proxyConfig hace que c.IP() devuelva la IP del cliente detrás de un proxy inverso;
+// sin ella todos los clientes compartirían el límite por IP del proxy. PROXY_HEADER
+// (p. ej. X-Real-IP) solo se lee si la conexión viene de TRUSTED_PROXIES (IPs o CIDR
+// separados por comas), y el proxy debe sobrescribirla, no añadir a la del cliente.
+func proxyConfig() fiber.Config {
+	header := strings.TrimSpace(os.Getenv("PROXY_HEADER"))
+	if header == "" {
+		return fiber.Config{}
+	}
+	var trusted []string
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			trusted = append(trusted, p)
+		}
+	}
+	if len(trusted) == 0 {
+		log.Printf("PROXY_HEADER=%s sin TRUSTED_PROXIES: se ignora la cabecera", header)
+	}
+	return fiber.Config{ProxyHeader: header, EnableTrustedProxyCheck: true, TrustedProxies: trusted, EnableIPValidation: true}
+}
+
+// authLimiter frena el login y el registro antes de llegar a bcrypt: límites por IP y
+// por cuenta, y bloqueo progresivo de la cuenta tras varios fallos seguidos.
+type authLimiter struct {
+	store RateLimitStore
+	// por acción ("login", "register", "forgot"): límite por IP y por cuenta
+	rules map[string][2]rateRule
+	// lockoutThreshold fallos dentro de lockoutWindow bloquean la cuenta lockoutBase,
+	// el doble en cada bloqueo seguido, hasta lockoutMax
+	lockoutThreshold int
+	lockoutWindow    time.Duration
+	lockoutBase      time.Duration
+	lockoutMax       time.Duration
+}
+
+// newAuthLimiterFromEnv usa PostgreSQL salvo RATE_LIMIT_STORE=memory
+func newAuthLimiterFromEnv(db *gorm.DB) *authLimiter {
+	var store RateLimitStore = &pgRateStore{db: db}
+	if getenv("RATE_LIMIT_STORE", "postgres") == "memory" {
+		store = newMemoryRateStore()
+	}
+	return &authLimiter{
+		store: store,
+		rules: map[string][2]rateRule{
+			"login": {
+				parseRateRule(os.Getenv("RATE_LIMIT_LOGIN_IP"), rateRule{20, 5 * time.Minute}),
+				parseRateRule(os.Getenv("RATE_LIMIT_LOGIN_ACCOUNT"), rateRule{10, 15 * time.Minute}),
+			},
+			"register": {
+				parseRateRule(os.Getenv("RATE_LIMIT_REGISTER_IP"), rateRule{5, time.Hour}),
+				parseRateRule(os.Getenv("RATE_LIMIT_REGISTER_ACCOUNT"), rateRule{3, time.Hour}),
+			},
+			// cada petición puede mandar un correo: el límite por cuenta evita inundar un buzón
+			"forgot": {
+				parseRateRule(os.Getenv("RATE_LIMIT_FORGOT_IP"), rateRule{10, time.Hour}),
+				parseRateRule(os.Getenv("RATE_LIMIT_FORGOT_ACCOUNT"), rateRule{3, time.Hour}),
+			},
+		},
+		lockoutThreshold: getenvInt("LOGIN_LOCKOUT_THRESHOLD", 5),
+		lockoutWindow:    getenvDuration("LOGIN_LOCKOUT_WINDOW", 15*time.Minute),
+		lockoutBase:      getenvDuration("LOGIN_LOCKOUT_BASE", time.Minute),
+		lockoutMax:       getenvDuration("LOGIN_LOCKOUT_MAX", time.Hour),
+	}
+}
+
+// limit aplica los límites de action a la IP y a la cuenta; devuelve la espera si se
+// superó alguno. Si el store falla se deja pasar: el login no debe caerse con él.
+func (l *authLimiter) limit(action, ip, email string) time.Duration {
+	rules := l.rules[action]
+	buckets := [2]string{action + ":ip:" + ip, action + ":account:" + normalizeEmail(email)}
+	now := time.Now()
+	for i, rule := range rules {
+		if rule.Limit == 0 {
+			continue
+		}
+		ok, wait, err := l.store.Allow(buckets[i], rule.Limit, rule.Window, now)
+		if err != nil {
+			log.Printf("rate limit %s no disponible: %v", buckets[i], err)
+			continue
+		}
+		if !ok {
+			return wait
+		}
+	}
+	return 0
+}
+
+// lockedFor devuelve cuánto le queda de bloqueo a la cuenta (0 si no está bloqueada)
+func (l *authLimiter) lockedFor(email string) time.Duration {
+	until, _, err := l.store.Lockout(normalizeEmail(email))
+	if err != nil {
+		log.Printf("no se pudo consultar el bloqueo de %s: %v", email, err)
+		return 0
+	}
+	return time.Until(until)
+}
+
+// loginFailed anota un fallo; al llegar al umbral bloquea la cuenta y devuelve la duración
+func (l *authLimiter) loginFailed(email string) time.Duration {
+	if l.lockoutThreshold == 0 {
+		return 0
+	}
+	account := normalizeEmail(email)
+	now := time.Now()
+	n, err := l.store.Add("login:failed:"+account, l.lockoutWindow, now)
+	if err != nil {
+		log.Printf("no se pudo anotar el fallo de login de %s: %v", account, err)
+		return 0
+	}
+	if n < l.lockoutThreshold {
+		return 0
+	}
+	until, level, err := l.store.Lockout(account)
+	if err != nil {
+		log.Printf("no se pudo consultar el bloqueo de %s: %v", account, err)
+		return 0
+	}
+	if until.Before(now.Add(-lockoutDecay)) {
+		level = 0
+	}
+	d := l.lockoutBase
+	for i := 0; i < level && d < l.lockoutMax; i++ {
+		d *= 2
+	}
+	if d > l.lockoutMax {
+		d = l.lockoutMax
+	}
+	if err := l.store.SetLockout(account, now.Add(d), level+1); err != nil {
+		log.Printf("no se pudo bloquear la cuenta %s: %v", account, err)
+		return 0
+	}
+	_ = l.store.Clear("login:failed:" + account)
+	return d
+}
+
+// reset olvida fallos y bloqueos de la cuenta (login correcto, desbloqueo o contraseña nueva)
+func (l *authLimiter) reset(email string) {
+	account := normalizeEmail(email)
+	if err := l.store.Clear("login:failed:" + account); err != nil {
+		log.Printf("no se pudieron borrar los fallos de login de %s: %v", account, err)
+	}
+	if err := l.store.ClearLockout(account); err != nil {
+		log.Printf("no se pudo desbloquear la cuenta %s: %v", account, err)
+	}
+}
+
+// purgeRateLimits borra periódicamente eventos y bloqueos que ya no cuentan
+func purgeRateLimits(l *authLimiter, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for range t.C {
+		if err := l.store.Purge(time.Now().Add(-lockoutDecay)); err != nil {
+			log.Printf("no se pudieron purgar los rate limits: %v", err)
+		}
+	}
+}
+
+// tooManyRequests responde 429 con Retry-After en segundos, redondeando hacia arriba
+func tooManyRequests(c *fiber.Ctx, wait time.Duration, message, code string) error {
+	secs := int((wait + time.Second - 1) / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(secs))
+	body := fiber.Map{"error": message, "retryAfter": secs}
+	if code != "" {
+		body["code"] = code
+	}
+	return c.Status(fiber.StatusTooManyRequests).JSON(body)
+}
+
+// UnlockToken es un enlace de desbloqueo. Como PasswordResetToken, se guarda solo el
+// hash, se gasta al usarlo y cada bloqueo nuevo invalida los enlaces anteriores.
+type UnlockToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+var errUnlockToken = errors.New("enlace inválido o vencido")
+
+// sendUnlockEmail avisa al dueño de la cuenta del bloqueo con un enlace para levantarlo.
+// Corre en segundo plano, así que los fallos van al log.
+func sendUnlockEmail(db *gorm.DB, mailer Mailer, appURL string, u *User, locked time.Duration) {
+	token, err := randomToken(32)
+	if err != nil {
+		log.Printf("no se pudo generar el enlace de desbloqueo del usuario %d: %v", u.ID, err)
+		return
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND used_at IS NULL", u.ID).Delete(&UnlockToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&UnlockToken{UserID: u.ID, TokenHash: hashToken(token), ExpiresAt: time.Now().Add(unlockTTL)}).Error
+	})
+	if err != nil {
+		log.Printf("no se pudo guardar el enlace de desbloqueo del usuario %d: %v", u.ID, err)
+		return
+	}
+	link := fmt.Sprintf("%s/desbloquear?token=%s", strings.TrimRight(appURL, "/"), token)
+	err = mailer.Send(u.Email, "Bloqueamos el acceso a tu cuenta de Cartesia", fmt.Sprintf(
+		"Hola %s,\n\nHubo varios intentos fallidos de iniciar sesión en tu cuenta, así que la bloqueamos durante %d min.\n\n"+
+			"Si fuiste tú, puedes desbloquearla ahora con este enlace:\n\n%s\n\n"+
+			"Si no fuiste tú, te recomendamos restablecer tu contraseña.\n",
+		u.Username, int((locked+time.Minute-1)/time.Minute), link))
+	if err != nil {
+		log.Printf("no se pudo enviar el correo a %s: %v", u.Email, err)
+	}
+}
+
+func registerUnlockRoutes(api fiber.Router, db *gorm.DB, limiter *authLimiter) {
+	api.Post("/auth/unlock", func(c *fiber.Ctx) error {
+		var body struct {
+			Token string `json:"token"`
+		}
+		if err := c.BodyParser(&body); err != nil || body.Token == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payload inválido"})
+		}
+		var userID uint
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var ut UnlockToken
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashToken(body.Token), time.Now()).First(&ut).Error; err != nil {
+				return errUnlockToken
+			}
+			userID = ut.UserID
+			return tx.Model(&ut).Update("used_at", time.Now()).Error
+		})
+		if errors.Is(err, errUnlockToken) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo procesar"})
+		}
+		var u User
+		if err := db.First(&u, userID).Error; err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": errUnlockToken.Error()})
+		}
+		limiter.reset(u.Email)
+		return c.JSON(fiber.Map{"ok": true})
+	})
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RateLimitStore guarda los eventos de las ventanas deslizantes y los bloqueos de
+// cuentas. La memoria sirve para pruebas y una sola instancia; con varias instancias
+// hay que usar la de PostgreSQL para que todas vean los mismos contadores.
+type RateLimitStore interface {
+	// Allow anota un evento en bucket si dentro de la ventana hay menos de limit; si no,
+	// devuelve cuánto falta para que el más antiguo salga de la ventana
+	Allow(bucket string, limit int, window time.Duration, now time.Time) (bool, time.Duration, error)
+	// Add anota un evento sin límite y devuelve cuántos quedan dentro de la ventana
+	Add(bucket string, window time.Duration, now time.Time) (int, error)
+	Clear(bucket string) error
+	// Lockout devuelve el bloqueo de la cuenta (cero si nunca la bloquearon)
+	Lockout(account string) (until time.Time, level int, err error)
+	SetLockout(account string, until time.Time, level int) error
+	ClearLockout(account string) error
+	// Purge borra eventos anteriores a before y bloqueos vencidos antes de before
+	Purge(before time.Time) error
+}
+
+type memoryLockout struct {
+	until time.Time
+	level int
+}
+
+type memoryRateStore struct {
+	mu     sync.Mutex
+	events map[string][]time.Time
+	locks  map[string]memoryLockout
+}
+
+func newMemoryRateStore() *memoryRateStore {
+	return &memoryRateStore{events: map[string][]time.Time{}, locks: map[string]memoryLockout{}}
+}
+
+// prune deja en el bucket solo los eventos posteriores a since (están en orden)
+func (s *memoryRateStore) prune(bucket string, since time.Time) []time.Time {
+	list := s.events[bucket]
+	i := 0
+	for i < len(list) && !list[i].After(since) {
+		i++
+	}
+	list = list[i:]
+	if len(list) == 0 {
+		delete(s.events, bucket)
+	} else {
+		s.events[bucket] = list
+	}
+	return list
+}
+
+func (s *memoryRateStore) Allow(bucket string, limit int, window time.Duration, now time.Time) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.prune(bucket, now.Add(-window))
+	if len(list) >= limit {
+		return false, list[0].Add(window).Sub(now), nil
+	}
+	s.events[bucket] = append(list, now)
+	return true, 0, nil
+}
+
+func (s *memoryRateStore) Add(bucket string, window time.Duration, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := append(s.prune(bucket, now.Add(-window)), now)
+	s.events[bucket] = list
+	return len(list), nil
+}
+
+func (s *memoryRateStore) Clear(bucket string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.events, bucket)
+	return nil
+}
+
+func (s *memoryRateStore) Lockout(account string) (time.Time, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := s.locks[account]
+	return l.until, l.level, nil
+}
+
+func (s *memoryRateStore) SetLockout(account string, until time.Time, level int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locks[account] = memoryLockout{until: until, level: level}
+	return nil
+}
+
+func (s *memoryRateStore) ClearLockout(account string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.locks, account)
+	return nil
+}
+
+func (s *memoryRateStore) Purge(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for bucket := range s.events {
+		s.prune(bucket, before)
+	}
+	for account, l := range s.locks {
+		if l.until.Before(before) {
+			delete(s.locks, account)
+		}
+	}
+	return nil
+}
+
+// RateLimitEvent es un intento dentro de una ventana deslizante (un registro por intento)
+type RateLimitEvent struct {
+	ID     uint      `gorm:"primaryKey" json:"id"`
+	Bucket string    `gorm:"size:320;not null;index:idx_rate_limit_bucket_at" json:"bucket"`
+	At     time.Time `gorm:"not null;index:idx_rate_limit_bucket_at" json:"at"`
+}
+
+// LoginLockout es el bloqueo vigente (o el último) de una cuenta; Level cuenta los
+// bloqueos seguidos para alargar el siguiente
+type LoginLockout struct {
+	Account     string    `gorm:"primaryKey;size:320" json:"account"`
+	Level       int       `gorm:"not null" json:"level"`
+	LockedUntil time.Time `gorm:"not null;index" json:"locked_until"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type pgRateStore struct {
+	db *gorm.DB
+}
+
+func (s *pgRateStore) Allow(bucket string, limit int, window time.Duration, now time.Time) (bool, time.Duration, error) {
+	var ok bool
+	var wait time.Duration
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// serializa el contar y anotar de un mismo bucket entre instancias
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", bucket).Error; err != nil {
+			return err
+		}
+		var row struct {
+			N      int
+			Oldest *time.Time
+		}
+		if err := tx.Model(&RateLimitEvent{}).Select("count(*) AS n, min(at) AS oldest").
+			Where("bucket = ? AND at > ?", bucket, now.Add(-window)).Scan(&row).Error; err != nil {
+			return err
+		}
+		if row.N >= limit && row.Oldest != nil {
+			wait = row.Oldest.Add(window).Sub(now)
+			return nil
+		}
+		ok = true
+		return tx.Create(&RateLimitEvent{Bucket: bucket, At: now}).Error
+	})
+	return ok, wait, err
+}
+
+func (s *pgRateStore) Add(bucket string, window time.Duration, now time.Time) (int, error) {
+	if err := s.db.Create(&RateLimitEvent{Bucket: bucket, At: now}).Error; err != nil {
+		return 0, err
+	}
+	var n int64
+	err := s.db.Model(&RateLimitEvent{}).Where("bucket = ? AND at > ?", bucket, now.Add(-window)).Count(&n).Error
+	return int(n), err
+}
+
+func (s *pgRateStore) Clear(bucket string) error {
+	return s.db.Where("bucket = ?", bucket).Delete(&RateLimitEvent{}).Error
+}
+
+func (s *pgRateStore) Lockout(account string) (time.Time, int, error) {
+	var l LoginLockout
+	if err := s.db.Where("account = ?", account).First(&l).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return time.Time{}, 0, nil
+		}
+		return time.Time{}, 0, err
+	}
+	return l.LockedUntil, l.Level, nil
+}
+
+func (s *pgRateStore) SetLockout(account string, until time.Time, level int) error {
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "account"}},
+		DoUpdates: clause.AssignmentColumns([]string{"level", "locked_until", "updated_at"}),
+	}).Create(&LoginLockout{Account: account, Level: level, LockedUntil: until}).Error
+}
+
+func (s *pgRateStore) ClearLockout(account string) error {
+	return s.db.Where("account = ?", account).Delete(&LoginLockout{}).Error
+}
+
+func (s *pgRateStore) Purge(before time.Time) error {
+	if err := s.db.Where("at < ?", before).Delete(&RateLimitEvent{}).Error; err != nil {
+		return err
+	}
+	return s.db.Where("locked_until < ?", before).Delete(&LoginLockout{}).Error
+}
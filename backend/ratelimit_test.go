@@ -0,0 +1,346 @@
+package main
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestMemoryRateStoreAllow(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	window := time.Minute
+	tests := []struct {
+		name     string
+		hits     []time.Duration // eventos ya anotados, relativos a t0
+		at       time.Duration
+		wantOK   bool
+		wantWait time.Duration
+	}{
+		{"vacío", nil, 0, true, 0},
+		{"por debajo del límite", []time.Duration{0, time.Second}, 2 * time.Second, true, 0},
+		{"en el límite", []time.Duration{0, time.Second, 2 * time.Second}, 3 * time.Second, false, 57 * time.Second},
+		{"el más antiguo sale justo al cumplir la ventana", []time.Duration{0, time.Second, 2 * time.Second}, window, true, 0},
+		{"un instante antes sigue dentro", []time.Duration{0, time.Second, 2 * time.Second}, window - time.Nanosecond, false, time.Nanosecond},
+		{"todos fuera de la ventana", []time.Duration{0, time.Second, 2 * time.Second}, 2 * window, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMemoryRateStore()
+			for _, h := range tt.hits {
+				if ok, _, _ := s.Allow("b", 3, window, t0.Add(h)); !ok {
+					t.Fatalf("evento previo en %s rechazado", h)
+				}
+			}
+			ok, wait, err := s.Allow("b", 3, window, t0.Add(tt.at))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.wantOK || wait != tt.wantWait {
+				t.Fatalf("Allow = %v, %s; se esperaba %v, %s", ok, wait, tt.wantOK, tt.wantWait)
+			}
+		})
+	}
+}
+
+func TestMemoryRateStoreAllowRejectedNotRecorded(t *testing.T) {
+	s := newMemoryRateStore()
+	t0 := time.Now()
+	s.Allow("b", 1, time.Minute, t0)
+	for i := 1; i <= 5; i++ {
+		if ok, _, _ := s.Allow("b", 1, time.Minute, t0.Add(time.Duration(i)*time.Second)); ok {
+			t.Fatalf("intento %d aceptado por encima del límite", i)
+		}
+	}
+	// los rechazos no alargan la espera
+	if ok, _, _ := s.Allow("b", 1, time.Minute, t0.Add(time.Minute)); !ok {
+		t.Fatal("rechazado después de vaciarse la ventana")
+	}
+}
+
+func TestMemoryRateStoreAdd(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		hits  []time.Duration
+		at    time.Duration
+		count int
+	}{
+		{"primero", nil, 0, 1},
+		{"acumula dentro de la ventana", []time.Duration{0, 10 * time.Second}, 20 * time.Second, 3},
+		{"descarta lo que sale de la ventana", []time.Duration{0, 10 * time.Second}, time.Minute, 2},
+		{"ventana vacía", []time.Duration{0, 10 * time.Second}, 2 * time.Minute, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMemoryRateStore()
+			for _, h := range tt.hits {
+				s.Add("b", time.Minute, t0.Add(h))
+			}
+			n, err := s.Add("b", time.Minute, t0.Add(tt.at))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != tt.count {
+				t.Fatalf("Add = %d; se esperaba %d", n, tt.count)
+			}
+		})
+	}
+}
+
+func testLimiter(store RateLimitStore) *authLimiter {
+	return &authLimiter{
+		store:            store,
+		rules:            map[string][2]rateRule{"login": {{Limit: 2, Window: time.Minute}, {Limit: 3, Window: time.Minute}}},
+		lockoutThreshold: 3,
+		lockoutWindow:    time.Minute,
+		lockoutBase:      time.Minute,
+		lockoutMax:       5 * time.Minute,
+	}
+}
+
+// failUntilLocked repite fallos hasta que uno bloquea la cuenta y devuelve esa duración
+func failUntilLocked(t *testing.T, l *authLimiter, email string) time.Duration {
+	t.Helper()
+	for i := 1; i <= l.lockoutThreshold; i++ {
+		d := l.loginFailed(email)
+		if i < l.lockoutThreshold && d != 0 {
+			t.Fatalf("bloqueada tras %d fallos", i)
+		}
+		if i == l.lockoutThreshold {
+			return d
+		}
+	}
+	return 0
+}
+
+func TestLoginFailedEscalation(t *testing.T) {
+	l := testLimiter(newMemoryRateStore())
+	// el doble en cada bloqueo seguido, con tope en lockoutMax
+	for i, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute} {
+		if got := failUntilLocked(t, l, "Ana@Example.com"); got != want {
+			t.Fatalf("bloqueo %d = %s; se esperaba %s", i+1, got, want)
+		}
+	}
+	if l.lockedFor("ana@example.com") <= 0 {
+		t.Fatal("la cuenta debería seguir bloqueada (la clave ignora mayúsculas)")
+	}
+	l.reset(" ana@example.com ")
+	if l.lockedFor("ana@example.com") > 0 {
+		t.Fatal("reset no levantó el bloqueo")
+	}
+	if got := failUntilLocked(t, l, "ana@example.com"); got != time.Minute {
+		t.Fatalf("tras reset el bloqueo = %s; se esperaba el base", got)
+	}
+}
+
+func TestLoginFailedDecay(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		until time.Time
+		level int
+		want  time.Duration
+	}{
+		{"último bloqueo reciente sigue escalando", now.Add(-time.Hour), 2, 4 * time.Minute},
+		{"pasado lockoutDecay vuelve al base", now.Add(-lockoutDecay - time.Minute), 2, time.Minute},
+		{"nivel alto queda en el tope", now.Add(-time.Hour), 10, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryRateStore()
+			store.SetLockout("ana@example.com", tt.until, tt.level)
+			l := testLimiter(store)
+			if got := failUntilLocked(t, l, "ana@example.com"); got != tt.want {
+				t.Fatalf("bloqueo = %s; se esperaba %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginFailedDisabled(t *testing.T) {
+	l := testLimiter(newMemoryRateStore())
+	l.lockoutThreshold = 0
+	for i := 0; i < 10; i++ {
+		if d := l.loginFailed("ana@example.com"); d != 0 {
+			t.Fatalf("bloqueo con lockout desactivado: %s", d)
+		}
+	}
+}
+
+func TestAuthLimiterLimit(t *testing.T) {
+	l := testLimiter(newMemoryRateStore())
+	// dos por IP, aunque cambie la cuenta
+	for i, email := range []string{"x@example.com", "y@example.com"} {
+		if wait := l.limit("login", "10.0.0.1", email); wait != 0 {
+			t.Fatalf("intento %d limitado", i+1)
+		}
+	}
+	if wait := l.limit("login", "10.0.0.1", "z@example.com"); wait <= 0 {
+		t.Fatal("la tercera petición desde la misma IP debería limitarse")
+	}
+	// tres por cuenta, aunque cambie la IP
+	for i, ip := range []string{"10.0.0.2", "10.0.0.3", "10.0.0.4"} {
+		if wait := l.limit("login", ip, "A@example.com"); wait != 0 {
+			t.Fatalf("intento %d de la cuenta limitado", i+1)
+		}
+	}
+	if wait := l.limit("login", "10.0.0.5", "a@example.com"); wait <= 0 {
+		t.Fatal("la cuenta debería limitarse desde cualquier IP")
+	}
+	// una acción sin reglas no limita
+	if wait := l.limit("register", "10.0.0.1", "a@example.com"); wait != 0 {
+		t.Fatal("register no tiene reglas en este limiter")
+	}
+}
+
+func TestTooManyRequestsRetryAfter(t *testing.T) {
+	tests := []struct {
+		wait time.Duration
+		want string
+	}{
+		{0, "1"},
+		{-time.Second, "1"},
+		{time.Millisecond, "1"},
+		{time.Second, "1"},
+		{time.Second + time.Nanosecond, "2"},
+		{59*time.Second + 500*time.Millisecond, "60"},
+		{time.Hour, "3600"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wait.String(), func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error { return tooManyRequests(c, tt.wait, "espera", "rate_limited") })
+			resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != fiber.StatusTooManyRequests {
+				t.Fatalf("status = %d", resp.StatusCode)
+			}
+			if got := resp.Header.Get(fiber.HeaderRetryAfter); got != tt.want {
+				t.Fatalf("Retry-After = %q; se esperaba %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRateRule(t *testing.T) {
+	def := rateRule{Limit: 7, Window: time.Minute}
+	tests := []struct {
+		in   string
+		want rateRule
+	}{
+		{"", def},
+		{"  ", def},
+		{"20/5m", rateRule{Limit: 20, Window: 5 * time.Minute}},
+		{" 3/1h ", rateRule{Limit: 3, Window: time.Hour}},
+		{"off", rateRule{}},
+		{"0", rateRule{}},
+		{"20", def},
+		{"x/5m", def},
+		{"20/x", def},
+		{"-1/5m", def},
+		{"5/0s", def},
+		{"5/-1m", def},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := parseRateRule(tt.in, def); got != tt.want {
+				t.Fatalf("parseRateRule(%q) = %+v; se esperaba %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyConfigIP(t *testing.T) {
+	// app.Test conecta desde 0.0.0.0
+	tests := []struct {
+		name    string
+		header  string
+		trusted string
+		want    string
+	}{
+		{"sin proxy se ignora la cabecera", "", "", "0.0.0.0"},
+		{"proxy de confianza", "X-Real-IP", "0.0.0.0, 10.0.0.0/8", "203.0.113.7"},
+		{"conexión que no es del proxy", "X-Real-IP", "10.0.0.0/8", "0.0.0.0"},
+		{"sin TRUSTED_PROXIES no se confía en nadie", "X-Real-IP", "", "0.0.0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PROXY_HEADER", tt.header)
+			t.Setenv("TRUSTED_PROXIES", tt.trusted)
+			app := fiber.New(proxyConfig())
+			app.Get("/", func(c *fiber.Ctx) error { return c.SendString(c.IP()) })
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-Real-IP", "203.0.113.7")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != tt.want {
+				t.Fatalf("c.IP() = %q; se esperaba %q", body, tt.want)
+			}
+		})
+	}
+}
+
+// linkMailer guarda el último correo en vez de enviarlo
+type linkMailer struct{ body string }
+
+func (m *linkMailer) Send(to, subject, body string) error {
+	m.body = body
+	return nil
+}
+
+func TestUnlockTokenSingleUse(t *testing.T) {
+	db := testDB(t, &User{}, &UnlockToken{})
+	u := testUser(t, db)
+	t.Cleanup(func() { db.Where("user_id = ?", u.ID).Delete(&UnlockToken{}) })
+	l := testLimiter(newMemoryRateStore())
+	app := fiber.New()
+	registerUnlockRoutes(app, db, l)
+	unlock := func(token string) int {
+		req := httptest.NewRequest("POST", "/auth/unlock", strings.NewReader(`{"token":"`+token+`"}`))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+	mail := &linkMailer{}
+	lockAndMail := func() string {
+		t.Helper()
+		failUntilLocked(t, l, u.Email)
+		sendUnlockEmail(db, mail, "https://cartesia.test", u, time.Minute)
+		_, rest, ok := strings.Cut(mail.body, "token=")
+		if !ok {
+			t.Fatalf("el correo no trae enlace: %q", mail.body)
+		}
+		return strings.Fields(rest)[0]
+	}
+
+	old := lockAndMail()
+	token := lockAndMail()
+	if code := unlock(old); code != fiber.StatusBadRequest {
+		t.Fatalf("enlace de un bloqueo anterior = %d; se esperaba 400", code)
+	}
+	if code := unlock(token); code != fiber.StatusOK {
+		t.Fatalf("desbloqueo = %d", code)
+	}
+	if l.lockedFor(u.Email) > 0 {
+		t.Fatal("la cuenta sigue bloqueada")
+	}
+	failUntilLocked(t, l, u.Email)
+	if code := unlock(token); code != fiber.StatusBadRequest {
+		t.Fatalf("reutilizar el enlace = %d; se esperaba 400", code)
+	}
+	if l.lockedFor(u.Email) <= 0 {
+		t.Fatal("un enlace usado no debería desbloquear otra vez")
+	}
+}
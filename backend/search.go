@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	suggestionLimit   = 5
+	suggestionMinLen  = 2
+	suggestionTimeout = 300 * time.Millisecond
+)
+
+// Expresiones de texto para la búsqueda. Tienen que coincidir letra a letra con las
+// de los índices de ensureSearchIndexes o Postgres no los usa.
+const (
+	roadmapSearchVector = `to_tsvector('spanish', coalesce(roadmaps.title, '') || ' ' || coalesce(roadmaps.description, ''))`
+	stepSearchVector    = `to_tsvector('spanish', coalesce(ps.title, '') || ' ' || coalesce(ps.description, ''))`
+)
+
+// ensureSearchIndexes crea los índices GIN de texto completo (idempotente)
+func ensureSearchIndexes(db *gorm.DB) error {
+	stmts := []string{
+		`CREATE INDEX IF NOT EXISTS idx_roadmaps_search ON roadmaps USING GIN (` + strings.ReplaceAll(roadmapSearchVector, "roadmaps.", "") + `)`,
+		`CREATE INDEX IF NOT EXISTS idx_path_steps_search ON path_steps USING GIN (` + strings.ReplaceAll(stepSearchVector, "ps.", "") + `)`,
+	}
+	for _, s := range stmts {
+		if err := db.Exec(s).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureSuggestionSupport prepara unaccent + pg_trgm para las sugerencias.
+// unaccent() no es IMMUTABLE y no sirve en un índice, de ahí el envoltorio f_unaccent.
+// Si falla (p. ej. sin permiso para crear extensiones) las sugerencias usan ILIKE.
+func ensureSuggestionSupport(db *gorm.DB) error {
+	stmts := []string{
+		`CREATE EXTENSION IF NOT EXISTS unaccent`,
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`CREATE OR REPLACE FUNCTION f_unaccent(text) RETURNS text
+			LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT
+			AS $$ SELECT public.unaccent('public.unaccent'::regdictionary, $1) $$`,
+		`CREATE INDEX IF NOT EXISTS idx_roadmaps_title_trgm ON roadmaps USING GIN (lower(f_unaccent(title)) gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_path_steps_title_trgm ON path_steps USING GIN (lower(f_unaccent(title)) gin_trgm_ops)`,
+	}
+	for _, s := range stmts {
+		if err := db.Exec(s).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// likeEscape escapa los comodines de LIKE
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// fuzzy indica si ensureSuggestionSupport terminó bien y existen f_unaccent y pg_trgm
+func registerSearchRoutes(api fiber.Router, db *gorm.DB, jwtSecret string, fuzzy bool) {
+	api.Get("/search/roadmaps", func(c *fiber.Ctx) error {
+		claims, err := optionalAuth(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		l, err := parseRoadmapListing(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var authorID, minSteps, maxSteps *int
+		for key, dst := range map[string]**int{"authorId": &authorID, "minSteps": &minSteps, "maxSteps": &maxSteps} {
+			v, ok := queryUint(c, key)
+			if !ok {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("%s inválido", key)})
+			}
+			*dst = v
+		}
+		text := strings.TrimSpace(c.Query("q"))
+
+		filtered := func() *gorm.DB {
+			q := db.Model(&Roadmap{}).Scopes(scopeVisibleTo(claimsUserID(claims)))
+			if text != "" {
+				q = q.Where("("+roadmapSearchVector+` @@ websearch_to_tsquery('spanish', ?)
+					OR EXISTS (SELECT 1 FROM path_steps ps WHERE ps.roadmap_id = roadmaps.id AND `+stepSearchVector+` @@ websearch_to_tsquery('spanish', ?)))`, text, text)
+			}
+			if authorID != nil {
+				q = q.Where("EXISTS (SELECT 1 FROM user_roadmaps ur WHERE ur.roadmap_id = roadmaps.id AND ur.user_id = ?)", *authorID)
+			}
+			if c.QueryBool("hasResources") {
+				q = q.Where("? > 0", diagramCountExpr(diagramResourcesPath))
+			}
+			if rt := strings.TrimSpace(c.Query("resourceType")); rt != "" {
+				q = q.Where("jsonb_path_exists(roadmaps.json_data, ?::jsonpath)", fmt.Sprintf(`$.*[*].data.resources[*] ? (@.type == %s)`, jsonpathString(rt)))
+			}
+			if minSteps != nil {
+				q = q.Where("? >= ?", diagramCountExpr(diagramStepsPath), *minSteps)
+			}
+			if maxSteps != nil {
+				q = q.Where("? <= ?", diagramCountExpr(diagramStepsPath), *maxSteps)
+			}
+			return q
+		}
+
+		if text == "" {
+			return l.respond(c, db, filtered, nil)
+		}
+		rank := gorm.Expr("ts_rank("+roadmapSearchVector+", websearch_to_tsquery('spanish', ?))", text)
+		return l.respond(c, db, filtered, &rank)
+	})
+
+	// autocompletar mientras se escribe: prefijo o parecido por trigramas, sin acentos
+	// (sin fuzzy, solo subcadena sin distinguir mayúsculas). Si la consulta falla o no
+	// entra en el presupuesto de tiempo se anota y se devuelve vacío.
+	api.Get("/search/suggestions", func(c *fiber.Ctx) error {
+		claims, err := optionalAuth(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		type roadmapSuggestion struct {
+			ID    uint   `json:"id"`
+			Title string `json:"title"`
+		}
+		type stepSuggestion struct {
+			ID             uint   `json:"id"`
+			Title          string `json:"title"`
+			LearningPathID uint   `json:"learningPathId"`
+		}
+		lps := []roadmapSuggestion{}
+		steps := []stepSuggestion{}
+		text := strings.TrimSpace(c.Query("q"))
+		if utf8.RuneCountInString(text) < suggestionMinLen {
+			return c.JSON(fiber.Map{"learningPaths": lps, "pathSteps": steps})
+		}
+		ctx, cancel := context.WithTimeout(c.Context(), suggestionTimeout)
+		defer cancel()
+		tx := db.WithContext(ctx)
+		like := likeEscape(text) + "%"
+		contains := "%" + likeEscape(text) + "%"
+		match := func(col string) (string, []interface{}) {
+			if !fuzzy {
+				return col + " ILIKE ?", []interface{}{contains}
+			}
+			norm := "lower(f_unaccent(" + col + "))"
+			return "(" + norm + " LIKE lower(f_unaccent(?)) OR " + norm + " % lower(f_unaccent(?)))",
+				[]interface{}{contains, text}
+		}
+		rank := func(col string) clause.Expr {
+			if !fuzzy {
+				return gorm.Expr("(CASE WHEN "+col+" ILIKE ? THEN 0 ELSE 1 END), length("+col+")", like)
+			}
+			norm := "lower(f_unaccent(" + col + "))"
+			return gorm.Expr("(CASE WHEN "+norm+" LIKE lower(f_unaccent(?)) THEN 0 ELSE 1 END), similarity("+norm+", lower(f_unaccent(?))) DESC", like, text)
+		}
+		failed := func(what string, err error) {
+			if ctx.Err() != nil {
+				log.Printf("sugerencias de %s: sin respuesta en %s", what, suggestionTimeout)
+				return
+			}
+			log.Printf("sugerencias de %s: %v", what, err)
+		}
+
+		cond, vars := match("roadmaps.title")
+		if err := tx.Model(&Roadmap{}).Scopes(scopeVisibleTo(claimsUserID(claims))).
+			Select("roadmaps.id, roadmaps.title").Where(cond, vars...).
+			Clauses(clause.OrderBy{Expression: rank("roadmaps.title")}).
+			Limit(suggestionLimit).Scan(&lps).Error; err != nil {
+			failed("roadmaps", err)
+			lps = []roadmapSuggestion{}
+		}
+		cond, vars = match("path_steps.title")
+		if err := tx.Model(&PathStep{}).Joins("JOIN roadmaps ON roadmaps.id = path_steps.roadmap_id").
+			Scopes(scopeVisibleTo(claimsUserID(claims))).
+			Select("path_steps.id, path_steps.title, path_steps.roadmap_id AS learning_path_id").
+			Where("path_steps.title <> ''").Where(cond, vars...).
+			Clauses(clause.OrderBy{Expression: rank("path_steps.title")}).
+			Limit(suggestionLimit).Scan(&steps).Error; err != nil {
+			failed("pasos", err)
+			steps = []stepSuggestion{}
+		}
+		return c.JSON(fiber.Map{"learningPaths": lps, "pathSteps": steps})
+	})
+}
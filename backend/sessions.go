@@ -0,0 +1,173 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// UserSession es un inicio de sesión (web o app Android) y corresponde a una familia
+// de refresh tokens. LastSeenAt se actualiza en cada refresh, así que tiene la
+// precisión de la vida del access token.
+type UserSession struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	FamilyID   string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	UserAgent  string     `gorm:"size:512" json:"user_agent"`
+	IP         string     `gorm:"size:64" json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"not null;index" json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// startSession abre una sesión nueva para u (login o registro) y emite sus tokens
+func startSession(db *gorm.DB, c *fiber.Ctx, secret string, u *User) (*AuthResponse, error) {
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	var resp *AuthResponse
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var rt *RefreshToken
+		var err error
+		if resp, rt, err = issueTokens(tx, secret, u, familyID); err != nil {
+			return err
+		}
+		ua := c.Get(fiber.HeaderUserAgent)
+		if len(ua) > 512 {
+			ua = ua[:512]
+		}
+		now := time.Now()
+		return tx.Create(&UserSession{UserID: u.ID, FamilyID: familyID, UserAgent: ua, IP: c.IP(), LastSeenAt: now, ExpiresAt: rt.ExpiresAt}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func touchSession(tx *gorm.DB, familyID, ip string, expiresAt time.Time) error {
+	return tx.Model(&UserSession{}).Where("family_id = ?", familyID).
+		Updates(map[string]interface{}{"last_seen_at": time.Now(), "ip": ip, "expires_at": expiresAt}).Error
+}
+
+// revokeUserSessions cierra todas las sesiones del usuario (p. ej. tras cambiar la contraseña)
+func revokeUserSessions(tx *gorm.DB, userID uint) error {
+	var families []string
+	if err := tx.Model(&UserSession{}).Where("user_id = ? AND revoked_at IS NULL", userID).Pluck("family_id", &families).Error; err != nil {
+		return err
+	}
+	for _, f := range families {
+		if err := revokeFamily(tx, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// describeDevice da un nombre legible al user agent para la lista de sesiones
+func describeDevice(ua string) string {
+	l := strings.ToLower(ua)
+	platform := "Desconocido"
+	switch {
+	case strings.Contains(l, "android"):
+		platform = "Android"
+	case strings.Contains(l, "iphone"), strings.Contains(l, "ipad"):
+		platform = "iOS"
+	case strings.Contains(l, "windows"):
+		platform = "Windows"
+	case strings.Contains(l, "mac os"):
+		platform = "macOS"
+	case strings.Contains(l, "linux"):
+		platform = "Linux"
+	}
+	// la app de Capacitor corre en un WebView, que se marca con "; wv)"
+	switch {
+	case strings.Contains(l, "; wv)"):
+		return "App " + platform
+	case strings.Contains(l, "edg/"):
+		return "Edge en " + platform
+	case strings.Contains(l, "firefox/"):
+		return "Firefox en " + platform
+	case strings.Contains(l, "chrome/"):
+		return "Chrome en " + platform
+	case strings.Contains(l, "safari/"):
+		return "Safari en " + platform
+	}
+	return platform
+}
+
+func registerSessionRoutes(api fiber.Router, db *gorm.DB, jwtSecret string) {
+	// sesiones abiertas del usuario; current marca la de la petición
+	api.Get("/me/sessions", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		var list []UserSession
+		if err := db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", claims.UserID, time.Now()).
+			Order("last_seen_at desc").Find(&list).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"items": []fiber.Map{}})
+		}
+		items := make([]fiber.Map, 0, len(list))
+		for _, s := range list {
+			items = append(items, fiber.Map{"id": s.ID, "device": describeDevice(s.UserAgent), "userAgent": s.UserAgent, "ip": s.IP,
+				"createdAt": s.CreatedAt, "lastSeenAt": s.LastSeenAt, "current": s.FamilyID == claims.SessionID})
+		}
+		return c.JSON(fiber.Map{"items": items})
+	})
+
+	api.Delete("/me/sessions/:id", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var s UserSession
+		if err := db.Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, claims.UserID).First(&s).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		if err := db.Transaction(func(tx *gorm.DB) error { return revokeFamily(tx, s.FamilyID) }); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo cerrar la sesión"})
+		}
+		return c.JSON(fiber.Map{"ok": true})
+	})
+
+	// cerrar sesión en todas partes; ?keepCurrent=true conserva la de esta petición
+	api.Delete("/me/sessions", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		q := db.Where("user_id = ? AND revoked_at IS NULL", claims.UserID)
+		if c.QueryBool("keepCurrent") && claims.SessionID != "" {
+			q = q.Where("family_id <> ?", claims.SessionID)
+		}
+		var list []UserSession
+		if err := q.Find(&list).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo cerrar la sesión"})
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			for _, s := range list {
+				if err := revokeFamily(tx, s.FamilyID); err != nil {
+					return err
+				}
+			}
+			// los tokens emitidos antes de existir las sesiones no tienen familia
+			if !c.QueryBool("keepCurrent") {
+				return revokeAccessToken(tx, claims.ID, claims.ExpiresAt.Time)
+			}
+			return nil
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo cerrar la sesión"})
+		}
+		return c.JSON(fiber.Map{"ok": true, "revoked": len(list)})
+	})
+}
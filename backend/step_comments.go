@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// StepComment cuelga del nodo (roadmap + node id) y no del PathStep, para que la
+// conversación sobreviva si el nodo se borra y vuelve con una restauración.
+type StepComment struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	RoadmapID uint      `gorm:"not null;index:idx_step_comment_node" json:"roadmap_id"`
+	NodeID    string    `gorm:"size:128;not null;index:idx_step_comment_node" json:"node_id"`
+	UserID    uint      `gorm:"index;not null" json:"user_id"`
+	ParentID  *uint     `gorm:"index" json:"parent_id"`
+	Content   string    `gorm:"type:text;not null" json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func registerStepCommentRoutes(api fiber.Router, db *gorm.DB, jwtSecret string) {
+	// loadStep busca el paso y su roadmap; escribe la respuesta de error si falla
+	loadStep := func(c *fiber.Ctx, claims *tokenClaims) (*PathStep, *Roadmap, error) {
+		id, err := paramID(c, "id")
+		if err != nil {
+			return nil, nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var s PathStep
+		if err := db.First(&s, id).Error; err != nil {
+			return nil, nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		var r Roadmap
+		if err := db.First(&r, s.RoadmapID).Error; err != nil {
+			return nil, nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		if !canAccess(db, claimsUserID(claims), &r, actionRead) {
+			return nil, nil, denyAccess(c, claims)
+		}
+		return &s, &r, nil
+	}
+
+	api.Get("/path-steps/:id/comments", func(c *fiber.Ctx) error {
+		claims, err := optionalAuth(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		s, _, err := loadStep(c, claims)
+		if s == nil {
+			return err
+		}
+		var comments []StepComment
+		if err := db.Where("roadmap_id = ? AND node_id = ?", s.RoadmapID, s.NodeID).Order("created_at asc").Find(&comments).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"items": []fiber.Map{}})
+		}
+		userIDs := make([]uint, 0, len(comments))
+		for _, cm := range comments {
+			userIDs = append(userIDs, cm.UserID)
+		}
+		var users []User
+		if len(userIDs) > 0 {
+			if err := db.Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+				users = []User{}
+			}
+		}
+		uname := map[uint]string{}
+		for _, u := range users {
+			uname[u.ID] = u.Username
+		}
+		// armar el árbol: respuestas en orden cronológico, hilos más recientes primero
+		nodes := map[uint]fiber.Map{}
+		children := map[uint][]uint{}
+		var roots []uint
+		for _, cm := range comments {
+			nodes[cm.ID] = fiber.Map{"id": cm.ID, "content": cm.Content, "createdAt": cm.CreatedAt, "username": uname[cm.UserID], "userId": cm.UserID, "parentId": cm.ParentID}
+			if cm.ParentID != nil {
+				if _, ok := nodes[*cm.ParentID]; ok {
+					children[*cm.ParentID] = append(children[*cm.ParentID], cm.ID)
+					continue
+				}
+			}
+			roots = append(roots, cm.ID)
+		}
+		var build func(id uint) fiber.Map
+		build = func(id uint) fiber.Map {
+			n := nodes[id]
+			replies := make([]fiber.Map, 0, len(children[id]))
+			for _, ch := range children[id] {
+				replies = append(replies, build(ch))
+			}
+			n["replies"] = replies
+			return n
+		}
+		items := make([]fiber.Map, 0, len(roots))
+		for i := len(roots) - 1; i >= 0; i-- {
+			items = append(items, build(roots[i]))
+		}
+		return c.JSON(fiber.Map{"items": items, "total": len(comments)})
+	})
+
+	api.Post("/path-steps/:id/comments", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		s, r, err := loadStep(c, claims)
+		if s == nil {
+			return err
+		}
+		if !emailVerified(db, claims.UserID) {
+			return denyUnverified(c)
+		}
+		// accept json or form
+		var body struct {
+			Content  string `json:"content"`
+			ParentID *uint  `json:"parentId"`
+		}
+		if c.Is("json") {
+			if err := json.Unmarshal(c.Body(), &body); err != nil {
+				body.Content = ""
+			}
+		} else {
+			body.Content = c.FormValue("content")
+			if raw := strings.TrimSpace(c.FormValue("parentId")); raw != "" {
+				n, err := strconv.ParseUint(raw, 10, 64)
+				if err != nil || n == 0 {
+					return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "comentario padre inválido"})
+				}
+				parentID := uint(n)
+				body.ParentID = &parentID
+			}
+		}
+		content := strings.TrimSpace(body.Content)
+		if content == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "contenido vacío"})
+		}
+		if body.ParentID != nil {
+			var parent StepComment
+			if err := db.Where("id = ? AND roadmap_id = ? AND node_id = ?", *body.ParentID, s.RoadmapID, s.NodeID).First(&parent).Error; err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "comentario padre inválido"})
+			}
+		}
+		cm := &StepComment{RoadmapID: s.RoadmapID, NodeID: s.NodeID, UserID: claims.UserID, ParentID: body.ParentID, Content: content}
+		if err := db.Create(cm).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo comentar"})
+		}
+		notifyOwners(db, r, claims.UserID, notifyComment, func(actor, title string) string {
+			return fmt.Sprintf("%s comentó un paso de «%s»", actor, title)
+		})
+		return c.JSON(fiber.Map{"id": cm.ID})
+	})
+
+	// conteo por nodo para pintar insignias en el editor
+	api.Get("/learning-paths/:id/steps/comment-counts", func(c *fiber.Ctx) error {
+		claims, err := optionalAuth(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var r Roadmap
+		if err := db.First(&r, id).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"items": []fiber.Map{}})
+		}
+		if !canAccess(db, claimsUserID(claims), &r, actionRead) {
+			return denyAccess(c, claims)
+		}
+		var rows []struct {
+			StepID uint
+			NodeID string
+			Count  int64
+		}
+		if err := db.Raw(`SELECT ps.id AS step_id, ps.node_id, COUNT(*) AS count
+			FROM step_comments sc JOIN path_steps ps ON ps.roadmap_id = sc.roadmap_id AND ps.node_id = sc.node_id
+			WHERE sc.roadmap_id = ? GROUP BY ps.id, ps.node_id`, r.ID).Scan(&rows).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"items": []fiber.Map{}})
+		}
+		items := make([]fiber.Map, 0, len(rows))
+		for _, rw := range rows {
+			items = append(items, fiber.Map{"stepId": rw.StepID, "nodeId": rw.NodeID, "count": rw.Count})
+		}
+		return c.JSON(fiber.Map{"items": items})
+	})
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	maxTagsPerRoadmap = 10
+	maxTagLen         = 40
+	tagSuggestLimit   = 10
+)
+
+var (
+	errTooManyTags = errors.New("demasiadas etiquetas")
+	errInvalidTag  = errors.New("etiqueta inválida")
+)
+
+// Tag guarda el nombre ya normalizado (ver normalizeTag), que es su identidad
+type Tag struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"size:64;uniqueIndex;not null" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RoadmapTag struct {
+	ID        uint `gorm:"primaryKey" json:"id"`
+	RoadmapID uint `gorm:"not null;uniqueIndex:idx_roadmap_tag" json:"roadmap_id"`
+	TagID     uint `gorm:"not null;uniqueIndex:idx_roadmap_tag;index" json:"tag_id"`
+}
+
+// TagAlias redirige un sinónimo (normalizado) a la etiqueta canónica; lo crean
+// los administradores al fusionar etiquetas.
+type TagAlias struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Alias     string    `gorm:"size:64;uniqueIndex;not null" json:"alias"`
+	TagID     uint      `gorm:"not null;index" json:"tag_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var stripMarks = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// normalizeTag deja la etiqueta en minúsculas, sin acentos y con guiones:
+// "Diseño  Web" -> "diseno-web". Conserva + # . para cosas como c++, c# o .net.
+func normalizeTag(s string) string {
+	s, _, _ = transform.String(stripMarks, strings.ToLower(strings.TrimSpace(s)))
+	var b strings.Builder
+	dash := false
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '+' || r == '#' || r == '.':
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		default:
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// normalizeTags normaliza, quita duplicados y aplica los límites
+func normalizeTags(names []string) ([]string, error) {
+	out := make([]string, 0, len(names))
+	seen := map[string]bool{}
+	for _, n := range names {
+		t := normalizeTag(n)
+		if t == "" || utf8.RuneCountInString(t) > maxTagLen {
+			return nil, errInvalidTag
+		}
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	if len(out) > maxTagsPerRoadmap {
+		return nil, errTooManyTags
+	}
+	return out, nil
+}
+
+// parseTagsPayload lee "tags" del cuerpo: array JSON o lista separada por comas en
+// formularios. nil significa que no vinieron y no hay que tocarlas.
+func parseTagsPayload(c *fiber.Ctx) ([]string, error) {
+	if c.Is("json") {
+		var body struct {
+			Tags *[]string `json:"tags"`
+		}
+		if err := json.Unmarshal(c.Body(), &body); err != nil {
+			var te *json.UnmarshalTypeError
+			if errors.As(err, &te) && te.Field == "tags" {
+				return nil, errInvalidTag
+			}
+			return nil, nil
+		}
+		if body.Tags == nil {
+			return nil, nil
+		}
+		return normalizeTags(*body.Tags)
+	}
+	v := c.FormValue("tags")
+	if v == "" {
+		return nil, nil
+	}
+	var names []string
+	for _, n := range strings.Split(v, ",") {
+		if strings.TrimSpace(n) != "" {
+			names = append(names, n)
+		}
+	}
+	return normalizeTags(names)
+}
+
+// resolveTag devuelve la etiqueta canónica para name (ya normalizado), creándola si hace falta
+func resolveTag(tx *gorm.DB, name string) (*Tag, error) {
+	var alias TagAlias
+	if err := tx.Where("alias = ?", name).First(&alias).Error; err == nil {
+		var t Tag
+		if err := tx.First(&t, alias.TagID).Error; err == nil {
+			return &t, nil
+		}
+	}
+	t := Tag{Name: name}
+	if err := tx.Where("name = ?", name).FirstOrCreate(&t).Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// setRoadmapTags deja al roadmap exactamente con esas etiquetas
+func setRoadmapTags(tx *gorm.DB, roadmapID uint, names []string) error {
+	ids := make([]uint, 0, len(names))
+	for _, n := range names {
+		t, err := resolveTag(tx, n)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, t.ID)
+	}
+	del := tx.Where("roadmap_id = ?", roadmapID)
+	if len(ids) > 0 {
+		del = del.Where("tag_id NOT IN ?", ids)
+	}
+	if err := del.Delete(&RoadmapTag{}).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&RoadmapTag{RoadmapID: roadmapID, TagID: id}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// roadmapTagNames carga las etiquetas de varios roadmaps de una vez
+func roadmapTagNames(db *gorm.DB, roadmapIDs []uint) map[uint][]string {
+	out := make(map[uint][]string, len(roadmapIDs))
+	for _, id := range roadmapIDs {
+		out[id] = []string{}
+	}
+	if len(roadmapIDs) == 0 {
+		return out
+	}
+	var rows []struct {
+		RoadmapID uint
+		Name      string
+	}
+	db.Table("roadmap_tags rt").Select("rt.roadmap_id, t.name").Joins("JOIN tags t ON t.id = rt.tag_id").
+		Where("rt.roadmap_id IN ?", roadmapIDs).Order("t.name").Scan(&rows)
+	for _, r := range rows {
+		out[r.RoadmapID] = append(out[r.RoadmapID], r.Name)
+	}
+	return out
+}
+
+// scopeTagged filtra por etiqueta, aceptando también sus alias
+func scopeTagged(tag string) func(*gorm.DB) *gorm.DB {
+	name := normalizeTag(tag)
+	return func(q *gorm.DB) *gorm.DB {
+		return q.Where(`EXISTS (SELECT 1 FROM roadmap_tags rt JOIN tags t ON t.id = rt.tag_id
+			WHERE rt.roadmap_id = roadmaps.id AND (t.name = ? OR t.id IN (SELECT ta.tag_id FROM tag_aliases ta WHERE ta.alias = ?)))`, name, name)
+	}
+}
+
+func registerTagRoutes(api fiber.Router, db *gorm.DB, jwtSecret string) {
+	// etiquetas en uso con su número de roadmaps visibles; ?q= autocompleta por prefijo
+	api.Get("/tags", func(c *fiber.Ctx) error {
+		claims, err := optionalAuth(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		limit := c.QueryInt("limit", 50)
+		if limit < 1 || limit > maxPageSize {
+			limit = maxPageSize
+		}
+		q := db.Table("tags").Select("tags.id, tags.name, COUNT(DISTINCT roadmaps.id) AS count").
+			Joins("JOIN roadmap_tags rt ON rt.tag_id = tags.id").
+			Joins("JOIN roadmaps ON roadmaps.id = rt.roadmap_id").
+			Scopes(scopeVisibleTo(claimsUserID(claims)))
+		if prefix := normalizeTag(c.Query("q")); prefix != "" {
+			like := likeEscape(prefix) + "%"
+			q = q.Where("(tags.name LIKE ? OR EXISTS (SELECT 1 FROM tag_aliases ta WHERE ta.tag_id = tags.id AND ta.alias LIKE ?))", like, like)
+			if c.Query("limit") == "" {
+				limit = tagSuggestLimit
+			}
+		}
+		var rows []struct {
+			ID    uint
+			Name  string
+			Count int64
+		}
+		if err := q.Group("tags.id, tags.name").Order("count DESC, tags.name").Limit(limit).Scan(&rows).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"items": []fiber.Map{}})
+		}
+		items := make([]fiber.Map, 0, len(rows))
+		for _, r := range rows {
+			items = append(items, fiber.Map{"id": r.ID, "name": r.Name, "count": r.Count})
+		}
+		return c.JSON(fiber.Map{"items": items})
+	})
+
+	// fusiona "from" en "into": los roadmaps pasan a la canónica y "from" queda como alias
+	api.Post("/tags/merge", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		var u User
+		if err := db.First(&u, claims.UserID).Error; err != nil || !u.IsAdmin {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden"})
+		}
+		var body struct {
+			From string `json:"from"`
+			Into string `json:"into"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payload inválido"})
+		}
+		from, into := normalizeTag(body.From), normalizeTag(body.Into)
+		if from == "" || into == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "faltan campos"})
+		}
+		var target *Tag
+		err = db.Transaction(func(tx *gorm.DB) error {
+			t, err := resolveTag(tx, into)
+			if err != nil {
+				return err
+			}
+			target = t
+			if from == t.Name {
+				return errInvalidTag
+			}
+			var src Tag
+			if err := tx.Where("name = ?", from).First(&src).Error; err == nil {
+				if err := tx.Exec(`UPDATE roadmap_tags SET tag_id = ? WHERE tag_id = ?
+					AND roadmap_id NOT IN (SELECT roadmap_id FROM roadmap_tags WHERE tag_id = ?)`, t.ID, src.ID, t.ID).Error; err != nil {
+					return err
+				}
+				if err := tx.Where("tag_id = ?", src.ID).Delete(&RoadmapTag{}).Error; err != nil {
+					return err
+				}
+				if err := tx.Model(&TagAlias{}).Where("tag_id = ?", src.ID).Update("tag_id", t.ID).Error; err != nil {
+					return err
+				}
+				if err := tx.Delete(&src).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "alias"}}, DoUpdates: clause.Assignments(map[string]interface{}{"tag_id": t.ID})}).
+				Create(&TagAlias{Alias: from, TagID: t.ID}).Error
+		})
+		if errors.Is(err, errInvalidTag) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "no se puede fusionar una etiqueta consigo misma"})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo fusionar"})
+		}
+		return c.JSON(fiber.Map{"ok": true, "tag": fiber.Map{"id": target.ID, "name": target.Name}})
+	})
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// snapshots que se conservan por roadmap; los más antiguos se descartan
+const maxDiagramVersions = 50
+
+var (
+	errDiagramLocked = errors.New("diagram locked by another user")
+	errStaleRevision = errors.New("diagram revision changed")
+)
+
+// RoadmapVersion es una foto inmutable del diagrama tras cada guardado
+type RoadmapVersion struct {
+	ID           uint        `gorm:"primaryKey" json:"id"`
+	RoadmapID    uint        `gorm:"index;not null" json:"roadmap_id"`
+	AuthorID     uint        `gorm:"not null" json:"author_id"`
+	Revision     uint        `gorm:"not null;default:0" json:"revision"`
+	JSONData     DiagramJSON `gorm:"type:jsonb" json:"-"`
+	RestoredFrom *uint       `json:"restored_from"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+type diagramSave struct {
+	AuthorID     uint
+	JSON         string
+	RestoredFrom *uint
+	// si no es nil solo se guarda cuando la revisión actual coincide (If-Match)
+	IfRevision *uint
+}
+
+// saveDiagram reemplaza el diagrama del roadmap (si nadie más tiene el lock y la
+// revisión esperada coincide) y registra la nueva versión. Todo guardado del
+// diagrama debe pasar por aquí. Al volver, r refleja el estado guardado.
+func saveDiagram(db *gorm.DB, r *Roadmap, s diagramSave) (*RoadmapVersion, error) {
+	v := &RoadmapVersion{RoadmapID: r.ID, AuthorID: s.AuthorID, JSONData: DiagramJSON(s.JSON), RestoredFrom: s.RestoredFrom}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		q := tx.Model(&Roadmap{}).
+			Where("id = ? AND (locked_by IS NULL OR locked_by = ? OR lock_expires_at IS NULL OR lock_expires_at <= ?)", r.ID, s.AuthorID, now)
+		if s.IfRevision != nil {
+			q = q.Where("revision = ?", *s.IfRevision)
+		}
+		// guardar también renueva el lease de quien lo tiene
+		res := q.UpdateColumns(map[string]interface{}{
+			"json_data":       DiagramJSON(s.JSON),
+			"revision":        gorm.Expr("revision + 1"),
+			"updated_at":      now,
+			"lock_expires_at": gorm.Expr("CASE WHEN locked_by = ? THEN ? ELSE lock_expires_at END", s.AuthorID, now.Add(lockTTL)),
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if err := tx.First(r, r.ID).Error; err != nil {
+			return err
+		}
+		if res.RowsAffected == 0 {
+			if lockedByOther(r, s.AuthorID, now) {
+				return errDiagramLocked
+			}
+			return errStaleRevision
+		}
+		v.Revision = r.Revision
+		if err := tx.Create(v).Error; err != nil {
+			return err
+		}
+		doc, err := parseDiagram(s.JSON)
+		if err != nil {
+			return err
+		}
+		if err := syncPathSteps(tx, r.ID, doc); err != nil {
+			return err
+		}
+		if err := tx.Exec(`DELETE FROM roadmap_versions WHERE roadmap_id = ? AND id NOT IN (
+			SELECT id FROM roadmap_versions WHERE roadmap_id = ? ORDER BY id DESC LIMIT ?)`, r.ID, r.ID, maxDiagramVersions).Error; err != nil {
+			return err
+		}
+		// una versión restaurada puede apuntar a otra que acabamos de podar: mejor sin
+		// restoredFrom que con un id que da 404
+		return tx.Exec(`UPDATE roadmap_versions SET restored_from = NULL WHERE roadmap_id = ? AND restored_from IS NOT NULL
+			AND restored_from NOT IN (SELECT id FROM roadmap_versions WHERE roadmap_id = ?)`, r.ID, r.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// diagramETag identifica la revisión del diagrama para If-Match / If-None-Match
+func diagramETag(r *Roadmap) string {
+	return fmt.Sprintf("\"%d\"", r.Revision)
+}
+
+// parseIfMatch devuelve la revisión pedida; nil si el header falta o es "*"
+func parseIfMatch(h string) (*uint, error) {
+	h = strings.TrimSpace(h)
+	if h == "" || h == "*" {
+		return nil, nil
+	}
+	h = strings.TrimPrefix(h, "W/")
+	n, err := strconv.ParseUint(strings.Trim(h, "\""), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	rev := uint(n)
+	return &rev, nil
+}
+
+func registerVersionRoutes(api fiber.Router, db *gorm.DB, jwtSecret string) {
+	api.Get("/learning-paths/:id/versions", func(c *fiber.Ctx) error {
+		claims, err := optionalAuth(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var r Roadmap
+		if err := db.First(&r, id).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"items": []fiber.Map{}})
+		}
+		if !canAccess(db, claimsUserID(claims), &r, actionRead) {
+			return denyAccess(c, claims)
+		}
+		var versions []RoadmapVersion
+		if err := db.Select("id", "roadmap_id", "author_id", "revision", "restored_from", "created_at").Where("roadmap_id = ?", r.ID).Order("id desc").Find(&versions).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"items": []fiber.Map{}})
+		}
+		userIDs := make([]uint, 0, len(versions))
+		for _, v := range versions {
+			userIDs = append(userIDs, v.AuthorID)
+		}
+		var users []User
+		if len(userIDs) > 0 {
+			if err := db.Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+				users = []User{}
+			}
+		}
+		uname := map[uint]string{}
+		for _, u := range users {
+			uname[u.ID] = u.Username
+		}
+		items := make([]fiber.Map, 0, len(versions))
+		for _, v := range versions {
+			items = append(items, fiber.Map{"id": v.ID, "createdAt": v.CreatedAt, "authorId": v.AuthorID, "authorUsername": uname[v.AuthorID], "restoredFrom": v.RestoredFrom, "revision": v.Revision})
+		}
+		return c.JSON(fiber.Map{"items": items})
+	})
+
+	api.Get("/learning-paths/:id/versions/:versionId", func(c *fiber.Ctx) error {
+		claims, err := optionalAuth(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var r Roadmap
+		if err := db.First(&r, id).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		if !canAccess(db, claimsUserID(claims), &r, actionRead) {
+			return denyAccess(c, claims)
+		}
+		versionID, err := paramID(c, "versionId")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var v RoadmapVersion
+		if err := db.Where("id = ? AND roadmap_id = ?", versionID, r.ID).First(&v).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "versión no encontrada"})
+		}
+		return c.JSON(fiber.Map{"id": v.ID, "createdAt": v.CreatedAt, "authorId": v.AuthorID, "restoredFrom": v.RestoredFrom, "revision": v.Revision, "diagramJSON": v.JSONData})
+	})
+
+	// :a y :b son ids de versión o "current" para el diagrama guardado ahora mismo
+	api.Get("/learning-paths/:id/versions/:a/diff/:b", func(c *fiber.Ctx) error {
+		claims, err := optionalAuth(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var r Roadmap
+		if err := db.First(&r, id).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		if !canAccess(db, claimsUserID(claims), &r, actionRead) {
+			return denyAccess(c, claims)
+		}
+		docs := make([]*diagramDocument, 2)
+		for i, key := range []string{"a", "b"} {
+			raw, err := versionDiagram(db, &r, c.Params(key))
+			if err != nil {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "versión no encontrada", "version": c.Params(key)})
+			}
+			if docs[i], err = parseDiagram(raw); err != nil {
+				return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "diagrama ilegible", "version": c.Params(key), "detail": err.Error()})
+			}
+		}
+		diff := diffDiagrams(docs[0], docs[1])
+		return c.JSON(fiber.Map{"from": c.Params("a"), "to": c.Params("b"), "nodes": diff.Nodes, "edges": diff.Edges})
+	})
+
+	// restaurar no reescribe el historial: crea una versión nueva con el contenido antiguo
+	api.Post("/learning-paths/:id/versions/:versionId/restore", func(c *fiber.Ctx) error {
+		claims, err := authenticate(c, db, jwtSecret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var r Roadmap
+		if err := db.First(&r, id).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no encontrado"})
+		}
+		if !canAccess(db, claims.UserID, &r, actionEditDiagram) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden"})
+		}
+		versionID, err := paramID(c, "versionId")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var old RoadmapVersion
+		if err := db.Where("id = ? AND roadmap_id = ?", versionID, r.ID).First(&old).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "versión no encontrada"})
+		}
+		// versiones anteriores a la validación pueden no pasarla
+		if _, issues := validateDiagram(string(old.JSONData)); len(issues) > 0 {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "diagrama inválido", "issues": issues})
+		}
+		v, err := saveDiagram(db, &r, diagramSave{AuthorID: claims.UserID, JSON: string(old.JSONData), RestoredFrom: &old.ID})
+		if errors.Is(err, errDiagramLocked) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "bloqueado por otro usuario", "lock": lockHolderJSON(db, &r)})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "no se pudo restaurar"})
+		}
+		c.Set(fiber.HeaderETag, diagramETag(&r))
+		return c.JSON(fiber.Map{"ok": true, "versionId": v.ID, "revision": r.Revision})
+	})
+}
+
+// versionDiagram devuelve el JSON de una versión del roadmap, o el actual si key es "current"
+func versionDiagram(db *gorm.DB, r *Roadmap, key string) (string, error) {
+	if key == "current" {
+		return string(r.JSONData), nil
+	}
+	id, err := strconv.ParseUint(key, 10, 64)
+	if err != nil {
+		return "", gorm.ErrRecordNotFound
+	}
+	var v RoadmapVersion
+	if err := db.Where("id = ? AND roadmap_id = ?", id, r.ID).First(&v).Error; err != nil {
+		return "", err
+	}
+	return string(v.JSONData), nil
+}
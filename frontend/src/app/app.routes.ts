@@ -14,6 +14,9 @@ export const routes: Routes = [
   { path: 'roadmaps/preview', loadComponent: () => import('./pages/preview/roadmap-preview.page').then(m => m.RoadmapPreviewPage) },
   { path: 'buscar', loadComponent: () => import('./pages/search/search.page').then(m => m.SearchPage) },
   { path: 'login', loadComponent: () => import('./pages/auth/login.page').then(m => m.LoginPage) },
+  { path: 'verificar', loadComponent: () => import('./pages/auth/verify.page').then(m => m.VerifyEmailPage) },
+  { path: 'desbloquear', loadComponent: () => import('./pages/auth/unlock.page').then(m => m.UnlockAccountPage) },
+  { path: 'recuperar', loadComponent: () => import('./pages/auth/recover.page').then(m => m.RecoverPasswordPage) },
   { path: 'register', loadComponent: () => import('./pages/auth/register.page').then(m => m.RegisterPage) },
   { path: 'tutor/aprende', loadComponent: () => import('./pages/tutor/learn.page').then(m => m.TutorLearnAIPage) },
   { path: 'tutor/aprende/profesores', loadComponent: () => import('./pages/tutor/teachers.page').then(m => m.TutorTeachersPage) },
@@ -1,7 +1,7 @@
 import { Component, OnInit, signal } from '@angular/core';
 import { CommonModule } from '@angular/common';
-import { RouterLink } from '@angular/router';
-import { ApiService, UserInfo } from '../../services/api.service';
+import { Router, RouterLink } from '@angular/router';
+import { ApiService, UserInfo, UserSession } from '../../services/api.service';
 
 @Component({
   selector: 'app-profile',
@@ -23,10 +23,31 @@ import { ApiService, UserInfo } from '../../services/api.service';
           </div>
         </div>
 
+        <div class="verify glass" *ngIf="user() && !user()?.emailVerified">
+          <span>Confirma tu correo para publicar roadmaps, comentar y valorar.</span>
+          <button class="btn ghost" (click)="resendVerification()" [disabled]="verifyMsg()">Reenviar correo</button>
+          <small class="muted" *ngIf="verifyMsg()">{{ verifyMsg() }}</small>
+        </div>
+
         <div class="actions">
           <a class="btn ghost" routerLink="/mis-roadmaps">Mis roadmaps</a>
           <a class="btn primary" routerLink="/roadmaps/editor">Nuevo roadmap</a>
         </div>
+
+        <div class="sessions">
+          <h2>Sesiones activas</h2>
+          <div class="session glass" *ngFor="let s of sessions()">
+            <div class="info">
+              <span class="value">{{ s.device }} <span class="tag" *ngIf="s.current">Este dispositivo</span></span>
+              <span class="muted">{{ s.ip }} · última actividad {{ s.lastSeenAt | date:'short' }}</span>
+            </div>
+            <button class="btn ghost" *ngIf="!s.current" (click)="revoke(s)">Cerrar</button>
+          </div>
+          <div class="actions">
+            <button class="btn ghost" (click)="signOutOthers()" [disabled]="sessions().length < 2">Cerrar las demás</button>
+            <button class="btn ghost" (click)="signOutEverywhere()">Cerrar sesión en todas partes</button>
+          </div>
+        </div>
       </ng-container>
 
       <ng-template #authPrompt>
@@ -54,12 +75,18 @@ import { ApiService, UserInfo } from '../../services/api.service';
     .center { text-align: center; }
     .cta { display:flex; gap: 12px; justify-content:center; margin-top: 8px; }
     .actions { display:flex; gap: 12px; margin-top: 16px; }
+    .verify { display:flex; flex-wrap:wrap; align-items:center; gap: 12px; margin-top: 16px; padding: 12px 16px; border-radius: 12px; border: 1px solid rgba(250,204,21,.35); }
+    .sessions { margin-top: 28px; }
+    .session { display:flex; justify-content:space-between; align-items:center; padding: 12px 16px; border-radius: 12px; margin-bottom: 8px; }
+    .tag { font-size: .75rem; font-weight: 500; padding: 2px 8px; border-radius: 999px; background: rgba(192,132,252,.2); margin-left: 6px; }
     `
   ]
 })
 export class ProfilePage implements OnInit {
   private _user = signal<UserInfo | null>(null);
-  constructor(public api: ApiService) {}
+  sessions = signal<UserSession[]>([]);
+  verifyMsg = signal('');
+  constructor(public api: ApiService, private router: Router) {}
 
   async ngOnInit() {
     if (!this.api.isAuthenticated()) return;
@@ -70,6 +97,49 @@ export class ProfilePage implements OnInit {
       // En caso de error, mantener la vista estable sin romper UI
       console.error('Error cargando perfil:', err);
     }
+    await this.loadSessions();
+  }
+
+  async loadSessions() {
+    try {
+      const res = await this.api.listSessions();
+      this.sessions.set(res.items);
+    } catch {}
+  }
+
+  async resendVerification() {
+    try {
+      await this.api.resendVerification();
+      this.verifyMsg.set(`Te enviamos un enlace a ${this._user()?.email}`);
+    } catch (err: any) {
+      if (err?.status === 429) {
+        this.verifyMsg.set(`Espera ${err?.error?.retryAfter || 60} s antes de pedir otro correo`);
+        setTimeout(() => this.verifyMsg.set(''), (err?.error?.retryAfter || 60) * 1000);
+      } else if (err?.status === 409) {
+        this._user.update(u => u ? { ...u, emailVerified: true } : u);
+      }
+    }
+  }
+
+  async revoke(s: UserSession) {
+    try {
+      await this.api.revokeSession(s.id);
+      this.sessions.update(list => list.filter(x => x.id !== s.id));
+    } catch {}
+  }
+
+  async signOutOthers() {
+    try {
+      await this.api.revokeAllSessions(true);
+      await this.loadSessions();
+    } catch {}
+  }
+
+  async signOutEverywhere() {
+    try {
+      await this.api.revokeAllSessions();
+      this.router.navigateByUrl('/login');
+    } catch {}
   }
 
   user() { return this._user(); }
@@ -103,7 +103,12 @@ export class LoginPage {
       this.success = true;
       setTimeout(async () => { await this.router.navigateByUrl('/'); }, 500);
     } catch (err: any) {
-      alert('Error al iniciar sesión');
+      // 429: demasiados intentos o cuenta bloqueada; el backend indica la espera
+      if (err?.status === 429) {
+        alert(`${err?.error?.error || 'Demasiados intentos'}. Prueba de nuevo en ${Math.ceil((err?.error?.retryAfter || 60) / 60)} min.`);
+      } else {
+        alert('Error al iniciar sesión');
+      }
       console.error(err);
     } finally {
       this.loading = false;
@@ -0,0 +1,120 @@
+import { Component, signal } from '@angular/core';
+import { CommonModule } from '@angular/common';
+import { FormsModule } from '@angular/forms';
+import { ActivatedRoute, Router, RouterLink } from '@angular/router';
+import { ApiService } from '../../services/api.service';
+
+// Sin ?token= pide el correo; con el token del enlace recibido permite elegir la contraseña nueva
+@Component({
+  selector: 'app-recover-password',
+  standalone: true,
+  imports: [CommonModule, FormsModule, RouterLink],
+  template: `
+    <div class="auth">
+      <main class="container">
+        <div class="card glass">
+          <ng-container *ngIf="!token; else resetForm">
+            <h1 class="center">Recuperar contraseña</h1>
+            <p class="subtitle center">Te enviaremos un enlace para elegir una contraseña nueva.</p>
+            <p class="success center" *ngIf="sent()">{{ sent() }}</p>
+            <form class="form" (ngSubmit)="requestLink()" novalidate *ngIf="!sent()">
+              <label class="field">
+                <span>Correo electrónico</span>
+                <input class="input" type="email" [(ngModel)]="email" name="email" placeholder="tu@correo.com" required />
+                <small class="error" *ngIf="submitted && !isValidEmail(email)">Correo inválido</small>
+              </label>
+              <button class="btn primary" type="submit" [disabled]="loading()">Enviar enlace</button>
+            </form>
+          </ng-container>
+
+          <ng-template #resetForm>
+            <h1 class="center">Nueva contraseña</h1>
+            <p class="success center" *ngIf="done()">Contraseña actualizada. Ya puedes iniciar sesión.</p>
+            <form class="form" (ngSubmit)="reset()" novalidate *ngIf="!done()">
+              <label class="field">
+                <span>Contraseña nueva</span>
+                <input class="input" type="password" [(ngModel)]="password" name="password" placeholder="••••••••" required minlength="8" />
+                <small class="error" *ngIf="submitted && password.length < 8">Mínimo 8 caracteres</small>
+              </label>
+              <label class="field">
+                <span>Repite la contraseña</span>
+                <input class="input" type="password" [(ngModel)]="confirm" name="confirm" placeholder="••••••••" required />
+                <small class="error" *ngIf="submitted && confirm !== password">Las contraseñas no coinciden</small>
+              </label>
+              <small class="error" *ngIf="error()">{{ error() }}</small>
+              <button class="btn primary" type="submit" [disabled]="loading()">Guardar contraseña</button>
+            </form>
+          </ng-template>
+
+          <p class="switch center"><a routerLink="/login">Volver a iniciar sesión</a></p>
+        </div>
+      </main>
+    </div>
+  `,
+  styles: [`
+    :host { display:block; }
+    .auth { min-height: calc(100svh - var(--nav-height)); padding: 0 24px; color: var(--color-text); background: linear-gradient(180deg, rgba(167,139,250,.08), rgba(167,139,250,.02)); overflow: hidden; }
+    .container { max-width: 560px; margin: 0 auto; padding-top: 24px; }
+    .card { border-radius: 16px; border:1px solid rgba(255,255,255,.14); background: rgba(17,24,39,.45); backdrop-filter: blur(8px) saturate(120%); box-shadow: 0 24px 60px rgba(16,10,43,.35); padding: 20px; }
+    h1 { margin:0 0 6px; font-size: 26px; }
+    .subtitle { margin:0 0 16px; color: var(--color-muted); }
+    .form { display:grid; gap: 12px; }
+    .field { display:grid; gap:8px; }
+    .field span { font-size:.9rem; color:#cbd5e1; }
+    .input { width:100%; padding:12px 14px; border-radius:12px; border:1px solid rgba(255,255,255,.18); background: rgba(255,255,255,.06); color: inherit; }
+    .input:focus { outline:0; border-color: rgba(255,255,255,.28); box-shadow: 0 0 0 3px rgba(255,255,255,.12); }
+    .error { color:#fca5a5; font-size:.85rem; }
+    .success { color:#86efac; font-size:.9rem; }
+    .btn { appearance:none; border:none; border-radius: 10px; font-weight:600; cursor:pointer; }
+    .primary { background: linear-gradient(90deg,#6d28d9,#5b21b6); color:#fff; border:1px solid rgba(255,255,255,.18); padding:10px 12px; width:100%; }
+    .switch { margin-top: 12px; color: var(--color-muted); }
+    .switch a { color: #c9b8ff; text-decoration: none; }
+  `]
+})
+export class RecoverPasswordPage {
+  token = '';
+  email = '';
+  password = '';
+  confirm = '';
+  submitted = false;
+  loading = signal(false);
+  sent = signal('');
+  done = signal(false);
+  error = signal('');
+
+  constructor(private api: ApiService, private router: Router, route: ActivatedRoute) {
+    this.token = route.snapshot.queryParamMap.get('token') || '';
+  }
+
+  isValidEmail(v: string): boolean { return /.+@.+\..+/.test(String(v||'').toLowerCase()); }
+
+  async requestLink() {
+    this.submitted = true;
+    if (!this.isValidEmail(this.email)) return;
+    this.loading.set(true);
+    try {
+      const res = await this.api.forgotPassword(this.email);
+      this.sent.set(res.message);
+    } catch {
+      this.sent.set('Si la cuenta existe, te enviamos un correo con instrucciones');
+    } finally {
+      this.loading.set(false);
+    }
+  }
+
+  async reset() {
+    this.submitted = true;
+    this.error.set('');
+    if (this.password.length < 8 || this.confirm !== this.password) return;
+    this.loading.set(true);
+    try {
+      await this.api.resetPassword(this.token, this.password);
+      this.done.set(true);
+      setTimeout(() => this.router.navigateByUrl('/login'), 1500);
+    } catch (err: any) {
+      this.error.set(err?.error?.error || 'No se pudo cambiar la contraseña');
+    } finally {
+      this.loading.set(false);
+    }
+  }
+}
@@ -119,7 +119,11 @@ export class RegisterPage {
       this.success = true;
       setTimeout(async () => { await this.router.navigateByUrl('/'); }, 500);
     } catch (err: any) {
-      alert('Error al registrar');
+      if (err?.status === 429) {
+        alert(`Demasiados registros desde aquí. Prueba de nuevo en ${Math.ceil((err?.error?.retryAfter || 60) / 60)} min.`);
+      } else {
+        alert(err?.error?.error || 'Error al registrar');
+      }
       console.error(err);
     } finally {
       this.loading = false;
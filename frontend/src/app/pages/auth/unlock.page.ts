@@ -0,0 +1,61 @@
+import { Component, OnInit, signal } from '@angular/core';
+import { CommonModule } from '@angular/common';
+import { ActivatedRoute, RouterLink } from '@angular/router';
+import { ApiService } from '../../services/api.service';
+
+// Destino del enlace del correo que avisa del bloqueo por intentos fallidos (/desbloquear?token=...)
+@Component({
+  selector: 'app-unlock-account',
+  standalone: true,
+  imports: [CommonModule, RouterLink],
+  template: `
+    <div class="auth">
+      <main class="container">
+        <div class="card glass center">
+          <h1>Desbloquear cuenta</h1>
+          <p class="subtitle" *ngIf="state() === 'loading'">Desbloqueando tu cuenta…</p>
+          <p class="success" *ngIf="state() === 'ok'">Listo, ya puedes volver a iniciar sesión.</p>
+          <p class="error" *ngIf="state() === 'error'">{{ error() }}</p>
+          <p class="switch">
+            <a routerLink="/login">Iniciar sesión</a>
+          </p>
+        </div>
+      </main>
+    </div>
+  `,
+  styles: [`
+    :host { display:block; }
+    .auth { min-height: calc(100svh - var(--nav-height)); padding: 0 24px; color: var(--color-text); background: linear-gradient(180deg, rgba(167,139,250,.08), rgba(167,139,250,.02)); overflow: hidden; }
+    .container { max-width: 560px; margin: 0 auto; padding-top: 24px; }
+    .card { border-radius: 16px; border:1px solid rgba(255,255,255,.14); background: rgba(17,24,39,.45); backdrop-filter: blur(8px) saturate(120%); box-shadow: 0 24px 60px rgba(16,10,43,.35); padding: 20px; }
+    .center { text-align: center; }
+    h1 { margin:0 0 6px; font-size: 26px; }
+    .subtitle { color: var(--color-muted); }
+    .error { color:#fca5a5; }
+    .success { color:#86efac; }
+    .switch { margin-top: 12px; }
+    .switch a { color: #c9b8ff; text-decoration: none; }
+  `]
+})
+export class UnlockAccountPage implements OnInit {
+  state = signal<'loading'|'ok'|'error'>('loading');
+  error = signal('');
+
+  constructor(private api: ApiService, private route: ActivatedRoute) {}
+
+  async ngOnInit() {
+    const token = this.route.snapshot.queryParamMap.get('token') || '';
+    if (!token) {
+      this.error.set('Falta el token del enlace');
+      this.state.set('error');
+      return;
+    }
+    try {
+      await this.api.unlockAccount(token);
+      this.state.set('ok');
+    } catch (err: any) {
+      this.error.set(err?.error?.error || 'No se pudo desbloquear la cuenta');
+      this.state.set('error');
+    }
+  }
+}
@@ -402,6 +402,7 @@ export class RoadmapEditorPage implements OnInit, OnDestroy {
   saveErr = false;
   canEdit = true;
   lockBusy = false;
+  private lockHeartbeat?: ReturnType<typeof setInterval>;
   collaborators: { userId:number; role:string }[] = [];
   inviteEmail = '';
   inviteRole: 'editor'|'collaborator'|'reader' = 'collaborator';
@@ -608,6 +609,7 @@ export class RoadmapEditorPage implements OnInit, OnDestroy {
   }
 
   ngOnDestroy(): void {
+    this.stopLockHeartbeat();
     if (this.learningPathId && this.api.isAuthenticated()) { this.api.unlockLearningPath(this.learningPathId).catch(() => {}); }
     this.graph?.dispose();
   }
@@ -758,8 +760,8 @@ export class RoadmapEditorPage implements OnInit, OnDestroy {
 
   async fetchLearningPaths(): Promise<void> {
     try {
-      const mine = await this.api.listMyLearningPaths();
-      this.paths = (mine || []).map((p: any) => ({ id: Number(p.id), title: String(p.title || '') }));
+      const mine = await this.api.listMyLearningPaths({ pageSize: 100, sortBy: 'updated_at' });
+      this.paths = (mine?.items || []).map((p: any) => ({ id: Number(p.id), title: String(p.title || '') }));
       
       if (this.learningPathId) {
         this.loadFromBackend();
@@ -775,9 +777,11 @@ export class RoadmapEditorPage implements OnInit, OnDestroy {
     try {
       await this.api.lockLearningPath(this.learningPathId);
       this.canEdit = true;
+      this.startLockHeartbeat();
       this.saveMsg = 'Bloqueado por ti'; this.saveOK = true; this.saveErr = false;
     } catch (e: any) {
       this.canEdit = false;
+      this.stopLockHeartbeat();
       if (e?.status === 423) { this.saveMsg = 'Bloqueado por otro usuario. Modo lectura'; this.saveErr = true; }
       else if (e?.status === 403) { this.saveMsg = 'Sin permiso de edición'; this.saveErr = true; }
       else { this.saveMsg = 'No se pudo bloquear'; this.saveErr = true; }
@@ -790,9 +794,11 @@ export class RoadmapEditorPage implements OnInit, OnDestroy {
     try {
       await this.api.lockLearningPath(this.learningPathId);
       this.canEdit = true;
+      this.startLockHeartbeat();
       this.saveMsg = 'Bloqueado por ti'; this.saveOK = true; this.saveErr = false;
     } catch (e: any) {
       this.canEdit = false;
+      this.stopLockHeartbeat();
       if (e?.status === 423) { this.saveMsg = 'Bloqueado por otro usuario'; this.saveErr = true; }
       else if (e?.status === 403) { this.saveMsg = 'Sin permiso de edición'; this.saveErr = true; }
       else { this.saveMsg = 'No se pudo bloquear'; this.saveErr = true; }
@@ -804,12 +810,31 @@ export class RoadmapEditorPage implements OnInit, OnDestroy {
     this.lockBusy = true;
     try {
       await this.api.unlockLearningPath(this.learningPathId);
+      this.stopLockHeartbeat();
       this.canEdit = false;
       this.saveMsg = 'Modo lectura'; this.saveOK = false; this.saveErr = false;
     } catch {}
     finally { this.lockBusy = false; }
   }
 
+  // El lock del backend es un lease: se renueva mientras el editor siga abierto
+  private startLockHeartbeat(): void {
+    this.stopLockHeartbeat();
+    this.lockHeartbeat = setInterval(() => {
+      if (!this.learningPathId) return;
+      this.api.lockLearningPath(this.learningPathId).catch((e: any) => {
+        this.stopLockHeartbeat();
+        this.canEdit = false;
+        this.saveMsg = e?.status === 423 ? 'Bloqueado por otro usuario. Modo lectura' : 'Se perdió el bloqueo';
+        this.saveOK = false; this.saveErr = true;
+      });
+    }, 60_000);
+  }
+
+  private stopLockHeartbeat(): void {
+    if (this.lockHeartbeat) { clearInterval(this.lockHeartbeat); this.lockHeartbeat = undefined; }
+  }
+
   async loadCollaborators(): Promise<void> {
     if (!this.learningPathId) return;
     try {
@@ -870,6 +895,14 @@ export class RoadmapEditorPage implements OnInit, OnDestroy {
           console.error('Guardar con fallback falló', err);
           this.saveOK = false; this.saveErr = true; this.saveMsg = 'No autorizado para guardar en ese roadmap';
         }
+      } else if (e?.status === 412) {
+        this.saveOK = false; this.saveErr = true; this.saveMsg = 'Otro usuario guardó cambios. Recarga antes de guardar';
+      } else if (e?.status === 422) {
+        const first = e?.error?.issues?.[0];
+        this.saveOK = false; this.saveErr = true;
+        this.saveMsg = first ? `Diagrama inválido: ${first.message}` : 'Diagrama inválido';
+      } else if (e?.status === 409) {
+        this.saveOK = false; this.saveErr = true; this.saveMsg = 'Bloqueado por otro usuario';
       } else {
         console.error('Error al guardar diagrama', e);
         this.saveOK = false; this.saveErr = true; this.saveMsg = 'Error al guardar';
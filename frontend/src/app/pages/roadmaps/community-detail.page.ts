@@ -1,7 +1,7 @@
 import { Component, ElementRef, OnDestroy, OnInit, ViewChild, signal } from '@angular/core';
 import { CommonModule } from '@angular/common';
 import { FormsModule } from '@angular/forms';
-import { ActivatedRoute } from '@angular/router';
+import { ActivatedRoute, Router } from '@angular/router';
 import { Graph, Node } from '@antv/x6';
 import { ApiService, LearningPath } from '../../services/api.service';
 
@@ -17,6 +17,7 @@ import { ApiService, LearningPath } from '../../services/api.service';
         <div class="meta" *ngIf="summary().createdAt">Publicado: {{summary().createdAt | date:'medium'}}</div>
         <div class="actions">
           <button class="btn" (click)="toggleLike()">{{ liked() ? 'Quitar me gusta' : 'Me gusta' }}</button>
+          <button class="btn" *ngIf="api.authState()" (click)="fork()" [disabled]="forking()">Crear mi copia</button>
           <div class="rating">
             <button class="star" [class.active]="myRating()>=1" (click)="onRate(1)"><i class="pi pi-star-fill"></i></button>
             <button class="star" [class.active]="myRating()>=2" (click)="onRate(2)"><i class="pi pi-star-fill"></i></button>
@@ -120,7 +121,8 @@ export class CommunityRoadmapDetailPage implements OnInit, OnDestroy {
   liked = signal(false);
   newComment = '';
   submitting = signal(false);
-  constructor(private route: ActivatedRoute, public api: ApiService) {}
+  forking = signal(false);
+  constructor(private route: ActivatedRoute, private router: Router, public api: ApiService) {}
   ngOnInit(): void {
     const idStr = this.route.snapshot.paramMap.get('id');
     this.lpId = idStr ? parseInt(idStr, 10) : 0;
@@ -143,5 +145,6 @@ export class CommunityRoadmapDetailPage implements OnInit, OnDestroy {
   async loadRatings(): Promise<void> { if (!this.lpId) return; try { const r = await this.api.getLearningPathRatings(this.lpId); this.ratingAvg.set(Number(r?.avg || 0)); } catch {} }
   async onRate(score: number): Promise<void> { if (!this.lpId) return; try { await this.api.rateLearningPath(this.lpId, score); this.myRating.set(score); await this.loadRatings(); } catch {} }
   toggleLike() { this.liked.update(v => !v); }
+  async fork(): Promise<void> { if (!this.lpId) return; this.forking.set(true); try { const lp = await this.api.forkLearningPath(this.lpId); this.router.navigate(['/roadmaps/editor'], { queryParams: { lp: lp.id } }); } catch {} finally { this.forking.set(false); } }
   async submitComment(): Promise<void> { if (!this.lpId || !this.newComment) return; this.submitting.set(true); try { await this.api.postRoadmapComment(this.lpId, this.newComment); this.newComment = ''; const r = await this.api.getLearningPathComments(this.lpId); this.comments.set(r.items || []); } catch {} finally { this.submitting.set(false); } }
 }
@@ -21,7 +21,7 @@ import { ApiService, LearningPath } from '../../services/api.service';
 
       <div class="toolbar">
         <input class="search" type="text" [(ngModel)]="query" (ngModelChange)="applyFilter()" placeholder="Buscar por título..." />
-        <select class="sort" [(ngModel)]="sort" (ngModelChange)="applyFilter()">
+        <select class="sort" [(ngModel)]="sort" (ngModelChange)="reload()">
           <option value="newest">Más nuevos</option>
           <option value="oldest">Más antiguos</option>
           <option value="title">Título</option>
@@ -41,6 +41,9 @@ import { ApiService, LearningPath } from '../../services/api.service';
         </article>
         <p *ngIf="filtered().length===0" class="muted">Sin resultados.</p>
       </div>
+      <div class="more" *ngIf="list().length < total">
+        <button class="btn" (click)="loadMore()" [disabled]="loading">Cargar más</button>
+      </div>
     </section>
   `,
   styles: [`
@@ -55,6 +58,7 @@ import { ApiService, LearningPath } from '../../services/api.service';
     .card { padding: 16px; border-radius: 14px; }
     .row { display:flex; align-items:center; gap:10px; justify-content:space-between; }
     .gap { gap: 8px; }
+    .more { display:flex; justify-content:center; margin-top: 16px; }
     .title { margin: 0; font-size: 1rem; }
     .desc { margin: 6px 0 10px; }
     .badge { padding:4px 8px; border-radius:999px; background:#22c55e; color:#0b1220; font-weight:700; font-size:.75rem; }
@@ -68,23 +72,34 @@ export class RoadmapsCommunityPage implements OnInit {
   filtered = signal<LearningPath[]>([]);
   query = '';
   sort: 'newest'|'oldest'|'title' = 'newest';
+  page = 0;
+  total = 0;
+  loading = false;
   constructor(private api: ApiService) {}
-  async ngOnInit() {
+  async ngOnInit() { await this.reload(); }
+  async reload() {
+    this.page = 0; this.total = 0;
+    this.list.set([]);
+    await this.loadMore();
+  }
+  async loadMore() {
+    if (this.loading) return;
+    this.loading = true;
+    const sortBy = this.sort === 'title' ? 'title' : 'created_at';
+    const sortDir = this.sort === 'newest' ? 'DESC' : 'ASC';
     try {
-      const items = await this.api.listPublicLearningPaths();
-      this.list.set(items || []);
-      this.applyFilter();
-    } catch (e) { this.list.set([]); this.filtered.set([]); }
+      const res = await this.api.listPublicLearningPaths({ page: this.page + 1, pageSize: 24, sortBy, sortDir });
+      this.page = res.page || this.page + 1;
+      this.total = res.total || 0;
+      this.list.set([...this.list(), ...(res.items || [])]);
+    } catch (e) { console.error('Error cargando roadmaps públicos', e); }
+    this.loading = false;
+    this.applyFilter();
   }
   applyFilter() {
     const q = this.query.trim().toLowerCase();
     let arr = this.list();
     if (q) arr = arr.filter(x => (x.title || '').toLowerCase().includes(q));
-    switch (this.sort) {
-      case 'title': arr = arr.slice().sort((a,b) => (a.title||'').localeCompare(b.title||'')); break;
-      case 'oldest': arr = arr.slice().sort((a,b) => (a.createdAt||'').localeCompare(b.createdAt||'')); break;
-      default: arr = arr.slice().sort((a,b) => (b.createdAt||'').localeCompare(a.createdAt||''));
-    }
     this.filtered.set(arr);
   }
 }
@@ -2,7 +2,7 @@ import { Component, OnInit, signal } from '@angular/core';
 import { CommonModule } from '@angular/common';
 import { FormsModule } from '@angular/forms';
 import { Router, RouterLink } from '@angular/router';
-import { ApiService, LearningPath } from '../../services/api.service';
+import { ApiService, LearningPath, RoadmapProgress } from '../../services/api.service';
 
 @Component({
   selector: 'app-my-roadmaps',
@@ -26,6 +26,10 @@ import { ApiService, LearningPath } from '../../services/api.service';
               <h3 class="title">{{ lp.title }}</h3>
             </div>
             <p class="desc" *ngIf="lp.description">{{ lp.description }}</p>
+            <div class="progress" *ngIf="progress()[lp.id] as p">
+              <div class="bar"><span [style.width.%]="p.percent"></span></div>
+              <span class="muted">{{ p.percent }}% · {{ p.completedSteps }}/{{ p.totalSteps }} pasos</span>
+            </div>
             <div class="row gap">
               <label>
                 <span class="muted">Visibilidad</span>
@@ -65,19 +69,27 @@ import { ApiService, LearningPath } from '../../services/api.service';
     .gap { gap: 8px; }
     .title { margin: 0; font-size: 1rem; }
     .desc { margin: 6px 0 10px; }
+    .progress { display:grid; gap:4px; margin-bottom: 10px; font-size: .8rem; }
+    .bar { height: 6px; border-radius: 999px; background: rgba(255,255,255,.12); overflow: hidden; }
+    .bar span { display:block; height: 100%; background: #22c55e; }
     .center { text-align: center; }
     .cta { display:flex; gap: 12px; justify-content:center; margin-top: 8px; }
   `]
 })
 export class MyRoadmapsPage implements OnInit {
   items = signal<LearningPath[]>([]);
+  progress = signal<Record<number, RoadmapProgress>>({});
   constructor(public api: ApiService, private router: Router) {}
 
   async ngOnInit() {
     if (!this.api.isAuthenticated()) return;
     try {
-      const list = await this.api.listMyLearningPaths();
-      this.items.set(list || []);
+      const list = await this.api.listMyLearningPaths({ pageSize: 100 });
+      this.items.set(list?.items || []);
+      const prog = await this.api.listMyProgress();
+      const byId: Record<number, RoadmapProgress> = {};
+      for (const p of prog?.items || []) byId[p.learningPathId] = p;
+      this.progress.set(byId);
     } catch (e) {
       console.error('Error cargando mis roadmaps', e);
       this.items.set([]);
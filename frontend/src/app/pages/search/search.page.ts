@@ -52,6 +52,7 @@ import { ApiService, LearningPath, SearchRoadmapsResponse } from '../../services
             </label>
             <label class="chip">Orden
               <select [(ngModel)]="sortBy" (change)="applySearch()">
+                <option value="relevance">Relevancia</option>
                 <option value="created_at">Fecha</option>
                 <option value="title">Título</option>
                 <option value="steps_count"># de pasos</option>
@@ -72,7 +73,8 @@ import { ApiService, LearningPath, SearchRoadmapsResponse } from '../../services
             </header>
             <div class="panel-section">
               <div class="section-title">Ordenar por</div>
-              <label class="radio"><input type="radio" name="sortBy" [(ngModel)]="sortBy" value="title" (change)="applySearch()" /> Mejor coincidencia (título)</label>
+              <label class="radio"><input type="radio" name="sortBy" [(ngModel)]="sortBy" value="relevance" (change)="applySearch()" /> Mejor coincidencia</label>
+              <label class="radio"><input type="radio" name="sortBy" [(ngModel)]="sortBy" value="title" (change)="applySearch()" /> Título</label>
               <label class="radio"><input type="radio" name="sortBy" [(ngModel)]="sortBy" value="created_at" (change)="applySearch()" /> El más nuevo</label>
               <label class="radio"><input type="radio" name="sortBy" [(ngModel)]="sortBy" value="steps_count" (change)="applySearch()" /> # de pasos</label>
             </div>
@@ -185,7 +187,7 @@ export class SearchPage implements OnInit {
   resourceType: string = '';
   minSteps?: number;
   maxSteps?: number;
-  sortBy: 'created_at'|'title'|'steps_count' = 'created_at';
+  sortBy: 'relevance'|'created_at'|'title'|'steps_count' = 'relevance';
   sortDir: 'ASC'|'DESC' = 'DESC';
   page = 1;
   pageSize = 12;
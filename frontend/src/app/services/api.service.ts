@@ -2,28 +2,74 @@ import { inject, Injectable, signal } from '@angular/core';
 import { HttpClient, HttpHeaders } from '@angular/common/http';
 import { firstValueFrom } from 'rxjs';
 
-export interface AuthResponse { token: string }
-export interface UserInfo { id: number; email: string; username: string }
+export interface AuthResponse { token: string; refreshToken?: string; expiresIn?: number }
+export interface UserInfo { id: number; email: string; username: string; emailVerified?: boolean }
 export interface DiagramData { nodes: any[]; edges: any[] }
-export interface LearningPath { id: number; title: string; description?: string; visibility?: 'public'|'private'; createdAt?: string; stepsCount?: number; resourcesCount?: number; thumbnail?: string; provider?: string }
+export interface LearningPath { id: number; title: string; description?: string; visibility?: 'public'|'private'; createdAt?: string; stepsCount?: number; resourcesCount?: number; thumbnail?: string; provider?: string; tags?: string[]; forksCount?: number; forkedFrom?: number | null }
 export interface ResourceUploadResponse { type: string; title?: string; url: string; mimeType?: string; size?: number; storagePath?: string }
-export interface SearchRoadmapsResponse { items: LearningPath[]; page: number; pageSize: number; total: number; sortBy: string; sortDir: 'ASC'|'DESC' }
+export interface StepComment { id: number; content: string; createdAt: string; username: string; userId: number; parentId: number | null; replies: StepComment[] }
+export interface RoadmapProgress { learningPathId: number; title: string; state: 'not_started'|'in_progress'|'completed'|'paused'; percent: number; completedSteps: number; totalSteps: number; startedAt?: string; completedAt?: string; completedNodes?: string[] }
+export interface LearningPathPage { items: LearningPath[]; page: number; pageSize: number; total: number; sortBy: string; sortDir: 'ASC'|'DESC' }
+export interface LearningPathListParams { page?: number; pageSize?: number; sortBy?: 'created_at'|'updated_at'|'title'|'steps_count'; sortDir?: 'ASC'|'DESC'; visibility?: 'public'|'private'; createdFrom?: string; createdTo?: string; tag?: string }
+export type SearchRoadmapsResponse = LearningPathPage;
+export interface CellChange { field: string; before?: any; after?: any }
+export interface UpstreamChange { id: string; kind: 'node'|'edge'; change: 'added'|'removed'|'modified'; conflict: boolean; summary: { id: string; type?: string; text?: string; source?: string; target?: string }; parentChanges?: CellChange[]; childChanges?: CellChange[] }
+export interface DiagramDiff { nodes: { added: any[]; removed: any[]; modified: any[] }; edges: { added: any[]; removed: any[]; modified: any[] } }
+export interface ChangeProposal { id: number; learningPathId: number; title: string; description?: string; state: 'open'|'accepted'|'rejected'|'withdrawn'; authorId: number; authorUsername: string; baseRevision: number; reviewerId?: number | null; reviewedAt?: string | null; versionId?: number | null; createdAt: string; updatedAt: string; commentsCount?: number }
+export interface ChangeProposalDetail extends ChangeProposal { diagramJSON: string; currentRevision: number; outdated: boolean; diff: DiagramDiff; comments: { id: number; content: string; createdAt: string; userId: number; username: string }[]; canReview: boolean }
+export interface AppNotification { id: number; type: 'comment'|'rating'|'invite'|'proposal_accepted'|'fork'|string; message: string; read: boolean; learningPathId?: number | null; actorId?: number | null; createdAt: string }
+export interface NotificationPage { items: AppNotification[]; page: number; pageSize: number; total: number; unread: number }
+export interface UserSession { id: number; device: string; userAgent: string; ip: string; createdAt: string; lastSeenAt: string; current: boolean }
+export interface UpstreamChanges { parentId: number; parentTitle: string; baseRevision: number; parentRevision: number; changes: UpstreamChange[]; conflicts: number }
 
 @Injectable({ providedIn: 'root' })
 export class ApiService {
   private http = inject(HttpClient);
   private baseUrl = 'http://localhost:8080/api/v1';
   private tokenKey = 'cartesia.token';
+  private refreshKey = 'cartesia.refresh';
+  private refreshing: Promise<string | null> | null = null;
   authState = signal<boolean>(!!localStorage.getItem('cartesia.token'));
 
   get token(): string | null { return localStorage.getItem(this.tokenKey); }
   set token(v: string | null) {
     if (v) localStorage.setItem(this.tokenKey, v);
-    else localStorage.removeItem(this.tokenKey);
+    else {
+      localStorage.removeItem(this.tokenKey);
+      localStorage.removeItem(this.refreshKey);
+    }
     this.authState.set(!!v);
   }
+  get refreshToken(): string | null { return localStorage.getItem(this.refreshKey); }
   isAuthenticated(): boolean { return !!this.token; }
 
+  private setSession(res: AuthResponse) {
+    if (res?.refreshToken) localStorage.setItem(this.refreshKey, res.refreshToken);
+    this.token = res?.token || null;
+  }
+
+  // Pide un access token nuevo con el refresh token; comparte la petición si ya hay una en curso
+  refreshSession(): Promise<string | null> {
+    const rt = this.refreshToken;
+    if (!rt) return Promise.resolve(null);
+    if (!this.refreshing) {
+      const url = `${this.baseUrl}/auth/refresh`;
+      this.refreshing = firstValueFrom(this.http.post<AuthResponse>(url, { refreshToken: rt }))
+        .then(res => { this.setSession(res); return res.token; })
+        .catch(() => { this.token = null; return null; })
+        .finally(() => { this.refreshing = null; });
+    }
+    return this.refreshing;
+  }
+
+  async logout(): Promise<void> {
+    const url = `${this.baseUrl}/auth/logout`;
+    try {
+      await firstValueFrom(this.http.post(url, { refreshToken: this.refreshToken }, { headers: this.authHeaders() }));
+    } catch {}
+    this.token = null;
+  }
+
   private authHeaders(): HttpHeaders {
     return new HttpHeaders({
       'Authorization': this.token ? `Bearer ${this.token}` : ''
@@ -32,15 +78,45 @@ export class ApiService {
 
   async register(payload: { email: string; username: string; password: string }): Promise<AuthResponse> {
     const url = `${this.baseUrl}/auth/register`;
-    return await firstValueFrom(this.http.post<AuthResponse>(url, payload));
+    const res = await firstValueFrom(this.http.post<AuthResponse>(url, payload));
+    this.setSession(res);
+    return res;
   }
 
   async login(payload: { email: string; password: string }): Promise<AuthResponse> {
     const url = `${this.baseUrl}/auth/login`;
-    return await firstValueFrom(this.http.post<AuthResponse>(url, payload));
+    const res = await firstValueFrom(this.http.post<AuthResponse>(url, payload));
+    this.setSession(res);
+    return res;
   }
 
 
+  async forgotPassword(email: string): Promise<{ ok: boolean; message: string }> {
+    const url = `${this.baseUrl}/auth/password/forgot`;
+    return await firstValueFrom(this.http.post<{ ok: boolean; message: string }>(url, { email }));
+  }
+
+  async resetPassword(token: string, password: string): Promise<{ ok: boolean }> {
+    const url = `${this.baseUrl}/auth/password/reset`;
+    return await firstValueFrom(this.http.post<{ ok: boolean }>(url, { token, password }));
+  }
+
+  async verifyEmail(token: string): Promise<{ ok: boolean }> {
+    const url = `${this.baseUrl}/auth/verify`;
+    return await firstValueFrom(this.http.post<{ ok: boolean }>(url, { token }));
+  }
+
+  // 429 con retryAfter (segundos) si se pidió otro correo hace poco
+  async resendVerification(): Promise<{ ok: boolean }> {
+    const url = `${this.baseUrl}/auth/verify/resend`;
+    return await firstValueFrom(this.http.post<{ ok: boolean }>(url, {}, { headers: this.authHeaders() }));
+  }
+
+  async unlockAccount(token: string): Promise<{ ok: boolean }> {
+    const url = `${this.baseUrl}/auth/unlock`;
+    return await firstValueFrom(this.http.post<{ ok: boolean }>(url, { token }));
+  }
+
   // Eliminado soporte de Supabase: intercambio de token descontinuado
 
   async me(): Promise<UserInfo> {
@@ -48,9 +124,13 @@ export class ApiService {
     return await firstValueFrom(this.http.get<UserInfo>(url, { headers: this.authHeaders() }));
   }
 
+  // Revisión del diagrama cargado por roadmap; se envía como If-Match al guardar
+  private diagramRevisions = new Map<number, number>();
+
   async getDiagram(learningPathId: number): Promise<DiagramData> {
     const url = `${this.baseUrl}/learning-paths/${learningPathId}/diagram`;
-    const res = await firstValueFrom(this.http.get<{ diagramJSON: string }>(url));
+    const res = await firstValueFrom(this.http.get<{ diagramJSON: string; revision?: number }>(url, { headers: this.authHeaders() }));
+    if (res?.revision != null) this.diagramRevisions.set(learningPathId, res.revision);
     try {
       const parsed = JSON.parse(res?.diagramJSON || '{"nodes":[],"edges":[]}');
       return parsed as DiagramData;
@@ -62,35 +142,147 @@ export class ApiService {
   async updateDiagram(learningPathId: number, diagram: DiagramData): Promise<boolean> {
     const url = `${this.baseUrl}/learning-paths/${learningPathId}/diagram`;
     const payload = { diagramJSON: JSON.stringify(diagram) };
-    const res = await firstValueFrom(this.http.put<{ ok: boolean }>(url, payload, { headers: this.authHeaders() }));
+    let headers = this.authHeaders();
+    const rev = this.diagramRevisions.get(learningPathId);
+    if (rev != null) headers = headers.set('If-Match', `"${rev}"`);
+    const res = await firstValueFrom(this.http.put<{ ok: boolean; revision?: number }>(url, payload, { headers }));
+    if (res?.revision != null) this.diagramRevisions.set(learningPathId, res.revision);
     return !!res?.ok;
   }
 
-  async listLearningPaths(): Promise<LearningPath[]> {
-    const url = `${this.baseUrl}/learning-paths`;
-    return await firstValueFrom(this.http.get<LearningPath[]>(url));
+  private listQuery(params: LearningPathListParams & { scope?: string } = {}): string {
+    const query: any = {};
+    for (const [k, v] of Object.entries(params)) {
+      if (v != null && v !== '') query[k] = String(v);
+    }
+    const qs = new URLSearchParams(query).toString();
+    return qs ? `?${qs}` : '';
+  }
+
+  async listLearningPaths(params?: LearningPathListParams & { scope?: 'mine'|'shared'|'public'|'all-visible' }): Promise<LearningPathPage> {
+    const url = `${this.baseUrl}/learning-paths${this.listQuery(params)}`;
+    return await firstValueFrom(this.http.get<LearningPathPage>(url, { headers: this.authHeaders() }));
   }
 
-  async listPublicLearningPaths(): Promise<LearningPath[]> {
-    const url = `${this.baseUrl}/public-learning-paths`;
-    return await firstValueFrom(this.http.get<LearningPath[]>(url));
+  async listPublicLearningPaths(params?: LearningPathListParams): Promise<LearningPathPage> {
+    const url = `${this.baseUrl}/public-learning-paths${this.listQuery(params)}`;
+    return await firstValueFrom(this.http.get<LearningPathPage>(url));
   }
 
-  async listMyLearningPaths(): Promise<LearningPath[]> {
-    const url = `${this.baseUrl}/learning-paths/mine`;
-    return await firstValueFrom(this.http.get<LearningPath[]>(url, { headers: this.authHeaders() }));
+  async listMyLearningPaths(params?: LearningPathListParams): Promise<LearningPathPage> {
+    const url = `${this.baseUrl}/learning-paths/mine${this.listQuery(params)}`;
+    return await firstValueFrom(this.http.get<LearningPathPage>(url, { headers: this.authHeaders() }));
   }
 
-  async createLearningPath(payload: { title: string; description?: string; visibility?: 'public'|'private' }): Promise<LearningPath> {
+  async createLearningPath(payload: { title: string; description?: string; visibility?: 'public'|'private'; tags?: string[] }): Promise<LearningPath> {
     const url = `${this.baseUrl}/learning-paths`;
     return await firstValueFrom(this.http.post<LearningPath>(url, payload, { headers: this.authHeaders() }));
   }
 
-  async updateLearningPath(id: number, data: { title?: string; description?: string; visibility?: 'public'|'private' }): Promise<LearningPath> {
+  async updateLearningPath(id: number, data: { title?: string; description?: string; visibility?: 'public'|'private'; tags?: string[] }): Promise<LearningPath> {
     const url = `${this.baseUrl}/learning-paths/${id}`;
     return await firstValueFrom(this.http.put<LearningPath>(url, data, { headers: this.authHeaders() }));
   }
 
+  async forkLearningPath(id: number, title?: string): Promise<LearningPath> {
+    const url = `${this.baseUrl}/learning-paths/${id}/fork`;
+    return await firstValueFrom(this.http.post<LearningPath>(url, title ? { title } : {}, { headers: this.authHeaders() }));
+  }
+
+  async listForks(id: number, params?: LearningPathListParams): Promise<LearningPathPage> {
+    const url = `${this.baseUrl}/learning-paths/${id}/forks${this.listQuery(params)}`;
+    return await firstValueFrom(this.http.get<LearningPathPage>(url, { headers: this.authHeaders() }));
+  }
+
+  async getUpstreamChanges(id: number): Promise<UpstreamChanges> {
+    const url = `${this.baseUrl}/learning-paths/${id}/upstream`;
+    return await firstValueFrom(this.http.get<UpstreamChanges>(url, { headers: this.authHeaders() }));
+  }
+
+  async mergeUpstream(id: number, changes: { id: string; kind?: 'node'|'edge'; resolution?: 'parent'|'child' }[]): Promise<{ ok: boolean; versionId: number; revision: number }> {
+    const url = `${this.baseUrl}/learning-paths/${id}/upstream/merge`;
+    return await firstValueFrom(this.http.post<{ ok: boolean; versionId: number; revision: number }>(url, { changes }, { headers: this.authHeaders() }));
+  }
+
+  async createProposal(id: number, payload: { title: string; description?: string; diagramJSON: string }): Promise<{ id: number; state: string; baseRevision: number }> {
+    const url = `${this.baseUrl}/learning-paths/${id}/proposals`;
+    return await firstValueFrom(this.http.post<{ id: number; state: string; baseRevision: number }>(url, payload, { headers: this.authHeaders() }));
+  }
+
+  async listProposals(id: number, state?: ChangeProposal['state']): Promise<{ items: ChangeProposal[] }> {
+    const q = state ? `?state=${state}` : '';
+    const url = `${this.baseUrl}/learning-paths/${id}/proposals${q}`;
+    return await firstValueFrom(this.http.get<{ items: ChangeProposal[] }>(url, { headers: this.authHeaders() }));
+  }
+
+  async getProposal(proposalId: number): Promise<ChangeProposalDetail> {
+    const url = `${this.baseUrl}/proposals/${proposalId}`;
+    return await firstValueFrom(this.http.get<ChangeProposalDetail>(url, { headers: this.authHeaders() }));
+  }
+
+  async updateProposal(proposalId: number, payload: { title?: string; description?: string; diagramJSON?: string }): Promise<{ ok: boolean }> {
+    const url = `${this.baseUrl}/proposals/${proposalId}`;
+    return await firstValueFrom(this.http.put<{ ok: boolean }>(url, payload, { headers: this.authHeaders() }));
+  }
+
+  async commentProposal(proposalId: number, content: string): Promise<{ id: number }> {
+    const url = `${this.baseUrl}/proposals/${proposalId}/comments`;
+    return await firstValueFrom(this.http.post<{ id: number }>(url, { content }, { headers: this.authHeaders() }));
+  }
+
+  async acceptProposal(proposalId: number, force = false): Promise<{ ok: boolean; versionId: number; revision: number }> {
+    const url = `${this.baseUrl}/proposals/${proposalId}/accept`;
+    return await firstValueFrom(this.http.post<{ ok: boolean; versionId: number; revision: number }>(url, { force }, { headers: this.authHeaders() }));
+  }
+
+  async closeProposal(proposalId: number, action: 'reject'|'withdraw', reason?: string): Promise<{ ok: boolean; state: string }> {
+    const url = `${this.baseUrl}/proposals/${proposalId}/${action}`;
+    return await firstValueFrom(this.http.post<{ ok: boolean; state: string }>(url, { reason }, { headers: this.authHeaders() }));
+  }
+
+  async listNotifications(params?: { page?: number; pageSize?: number; unread?: boolean }): Promise<NotificationPage> {
+    const q = new URLSearchParams();
+    if (params?.page) q.set('page', String(params.page));
+    if (params?.pageSize) q.set('pageSize', String(params.pageSize));
+    if (params?.unread) q.set('unread', 'true');
+    const qs = q.toString();
+    const url = `${this.baseUrl}/notifications${qs ? `?${qs}` : ''}`;
+    return await firstValueFrom(this.http.get<NotificationPage>(url, { headers: this.authHeaders() }));
+  }
+
+  async getUnreadNotificationCount(): Promise<{ count: number }> {
+    const url = `${this.baseUrl}/notifications/unread-count`;
+    return await firstValueFrom(this.http.get<{ count: number }>(url, { headers: this.authHeaders() }));
+  }
+
+  async markNotificationRead(id: number): Promise<{ ok: boolean }> {
+    const url = `${this.baseUrl}/notifications/${id}/read`;
+    return await firstValueFrom(this.http.post<{ ok: boolean }>(url, {}, { headers: this.authHeaders() }));
+  }
+
+  async markAllNotificationsRead(): Promise<{ ok: boolean; updated: number }> {
+    const url = `${this.baseUrl}/notifications/read-all`;
+    return await firstValueFrom(this.http.post<{ ok: boolean; updated: number }>(url, {}, { headers: this.authHeaders() }));
+  }
+
+  async listSessions(): Promise<{ items: UserSession[] }> {
+    const url = `${this.baseUrl}/me/sessions`;
+    return await firstValueFrom(this.http.get<{ items: UserSession[] }>(url, { headers: this.authHeaders() }));
+  }
+
+  async revokeSession(id: number): Promise<{ ok: boolean }> {
+    const url = `${this.baseUrl}/me/sessions/${id}`;
+    return await firstValueFrom(this.http.delete<{ ok: boolean }>(url, { headers: this.authHeaders() }));
+  }
+
+  // Cierra todas las sesiones; con keepCurrent conserva la de este dispositivo
+  async revokeAllSessions(keepCurrent = false): Promise<{ ok: boolean; revoked: number }> {
+    const url = `${this.baseUrl}/me/sessions${keepCurrent ? '?keepCurrent=true' : ''}`;
+    const res = await firstValueFrom(this.http.delete<{ ok: boolean; revoked: number }>(url, { headers: this.authHeaders() }));
+    if (!keepCurrent) this.token = null;
+    return res;
+  }
+
   async deleteLearningPath(id: number): Promise<{ ok: boolean }> {
     const url = `${this.baseUrl}/learning-paths/${id}`;
     return await firstValueFrom(this.http.delete<{ ok: boolean }>(url, { headers: this.authHeaders() }));
@@ -116,16 +308,42 @@ export class ApiService {
     return await firstValueFrom(this.http.post<{ token: string }>(url, { email, role }, { headers: this.authHeaders() }));
   }
 
+  async getProgress(id: number): Promise<RoadmapProgress> {
+    const url = `${this.baseUrl}/learning-paths/${id}/progress`;
+    return await firstValueFrom(this.http.get<RoadmapProgress>(url, { headers: this.authHeaders() }));
+  }
+
+  async setProgressState(id: number, action: 'start'|'pause'|'complete'): Promise<RoadmapProgress> {
+    const url = `${this.baseUrl}/learning-paths/${id}/progress/${action}`;
+    return await firstValueFrom(this.http.post<RoadmapProgress>(url, {}, { headers: this.authHeaders() }));
+  }
+
+  async setNodeDone(id: number, nodeId: string, done: boolean): Promise<RoadmapProgress> {
+    const url = `${this.baseUrl}/learning-paths/${id}/progress/nodes/${encodeURIComponent(nodeId)}`;
+    return await firstValueFrom(this.http.put<RoadmapProgress>(url, { done }, { headers: this.authHeaders() }));
+  }
+
+  async listMyProgress(): Promise<{ items: RoadmapProgress[] }> {
+    const url = `${this.baseUrl}/me/progress`;
+    return await firstValueFrom(this.http.get<{ items: RoadmapProgress[] }>(url, { headers: this.authHeaders() }));
+  }
+
+  async listTags(q?: string): Promise<{ items: { id:number; name:string; count:number }[] }> {
+    const url = `${this.baseUrl}/tags${q ? `?q=${encodeURIComponent(q)}` : ''}`;
+    return await firstValueFrom(this.http.get<{ items: { id:number; name:string; count:number }[] }>(url, { headers: this.authHeaders() }));
+  }
+
   async acceptInvitation(token: string): Promise<{ ok: boolean }> {
     const url = `${this.baseUrl}/learning-paths/invitations/${encodeURIComponent(token)}/accept`;
     return await firstValueFrom(this.http.post<{ ok: boolean }>(url, {}, { headers: this.authHeaders() }));
   }
 
   // RF-006: Búsqueda avanzada de roadmaps
-  async searchRoadmaps(params: { q?: string; authorId?: number; hasResources?: boolean; resourceType?: string; minSteps?: number; maxSteps?: number; sortBy?: 'created_at'|'title'|'steps_count'; sortDir?: 'ASC'|'DESC'; page?: number; pageSize?: number }): Promise<SearchRoadmapsResponse> {
+  async searchRoadmaps(params: { q?: string; tag?: string; authorId?: number; hasResources?: boolean; resourceType?: string; minSteps?: number; maxSteps?: number; sortBy?: 'relevance'|'created_at'|'title'|'steps_count'; sortDir?: 'ASC'|'DESC'; page?: number; pageSize?: number }): Promise<SearchRoadmapsResponse> {
     const url = `${this.baseUrl}/search/roadmaps`;
     const query: any = {};
     if (params.q) query.q = params.q;
+    if (params.tag) query.tag = params.tag;
     if (params.authorId != null) query.authorId = String(params.authorId);
     if (params.hasResources != null) query.hasResources = String(params.hasResources);
     if (params.resourceType) query.resourceType = params.resourceType;
@@ -184,9 +402,19 @@ export class ApiService {
     return await firstValueFrom(this.http.post<{ ok: boolean }>(url, options, { headers: this.authHeaders() }));
   }
 
-  async postPathStepComment(id: number, content: string): Promise<{ id: number }> {
+  async postPathStepComment(id: number, content: string, parentId?: number): Promise<{ id: number }> {
     const url = `${this.baseUrl}/path-steps/${id}/comments`;
-    return await firstValueFrom(this.http.post<{ id: number }>(url, { content }, { headers: this.authHeaders() }));
+    return await firstValueFrom(this.http.post<{ id: number }>(url, { content, parentId }, { headers: this.authHeaders() }));
+  }
+
+  async getPathStepComments(id: number): Promise<{ items: StepComment[]; total: number }> {
+    const url = `${this.baseUrl}/path-steps/${id}/comments`;
+    return await firstValueFrom(this.http.get<{ items: StepComment[]; total: number }>(url, { headers: this.authHeaders() }));
+  }
+
+  async getStepCommentCounts(id: number): Promise<{ items: { stepId:number; nodeId:string; count:number }[] }> {
+    const url = `${this.baseUrl}/learning-paths/${id}/steps/comment-counts`;
+    return await firstValueFrom(this.http.get<{ items: { stepId:number; nodeId:string; count:number }[] }>(url, { headers: this.authHeaders() }));
   }
 
   async rateResource(id: number, score: number): Promise<{ ok: boolean }> {
@@ -0,0 +1,22 @@
+import { inject } from '@angular/core';
+import { HttpErrorResponse, HttpInterceptorFn } from '@angular/common/http';
+import { catchError, from, switchMap, throwError } from 'rxjs';
+import { ApiService } from './api.service';
+
+// Ante un 401 renueva el access token con el refresh token y repite la petición una vez
+export const authInterceptor: HttpInterceptorFn = (req, next) => {
+  if (req.url.includes('/auth/')) return next(req);
+  const api = inject(ApiService);
+  return next(req).pipe(
+    catchError((err: unknown) => {
+      if (!(err instanceof HttpErrorResponse) || err.status !== 401 || !api.refreshToken) {
+        return throwError(() => err);
+      }
+      return from(api.refreshSession()).pipe(
+        switchMap(token => token
+          ? next(req.clone({ setHeaders: { Authorization: `Bearer ${token}` } }))
+          : throwError(() => err))
+      );
+    })
+  );
+};
@@ -1,7 +1,7 @@
 import { Component, signal, effect } from '@angular/core';
 import { CommonModule } from '@angular/common';
 import { Router, RouterLink } from '@angular/router';
-import { ApiService } from '../../../services/api.service';
+import { ApiService, AppNotification } from '../../../services/api.service';
 
 @Component({
   selector: 'app-navbar',
@@ -15,6 +15,9 @@ export class NavbarComponent {
   tutorOpen = signal(false);
   accountOpen = signal(false);
   langOpen = signal(false);
+  notifOpen = signal(false);
+  unreadCount = signal(0);
+  notifications = signal<AppNotification[]>([]);
   username = '';
   avatarInitial = 'U';
 
@@ -34,9 +37,12 @@ export class NavbarComponent {
           this.username = u?.username || '';
           this.avatarInitial = (this.username || 'U').charAt(0).toUpperCase();
         }).catch(() => {});
+        this.refreshUnread();
       } else {
         this.username = '';
         this.avatarInitial = 'U';
+        this.unreadCount.set(0);
+        this.notifications.set([]);
       }
     });
     const saved = localStorage.getItem('lang');
@@ -48,6 +54,39 @@ export class NavbarComponent {
   toggleAccount() { this.accountOpen.update(v => !v); }
   toggleLang() { this.langOpen.update(v => !v); }
 
+  refreshUnread() {
+    this.api.getUnreadNotificationCount().then(r => this.unreadCount.set(r.count)).catch(() => {});
+  }
+
+  toggleNotifications() {
+    this.notifOpen.update(v => !v);
+    if (!this.notifOpen()) return;
+    this.api.listNotifications({ pageSize: 10 }).then(r => {
+      this.notifications.set(r.items);
+      this.unreadCount.set(r.unread);
+    }).catch(() => {});
+  }
+
+  async markAllRead() {
+    try {
+      await this.api.markAllNotificationsRead();
+      this.notifications.update(list => list.map(n => ({ ...n, read: true })));
+      this.unreadCount.set(0);
+    } catch {}
+  }
+
+  async openNotification(n: AppNotification) {
+    if (!n.read) {
+      try {
+        await this.api.markNotificationRead(n.id);
+        this.notifications.update(list => list.map(x => x.id === n.id ? { ...x, read: true } : x));
+        this.unreadCount.update(v => Math.max(0, v - 1));
+      } catch {}
+    }
+    this.notifOpen.set(false);
+    if (n.learningPathId) this.router.navigateByUrl(`/roadmaps/comunidad/${n.learningPathId}`);
+  }
+
   closeRoadmapsOnBlur(event: FocusEvent) {
     const target = event.target as HTMLElement;
     setTimeout(() => {
@@ -77,11 +116,9 @@ export class NavbarComponent {
     this.router.navigateByUrl('/');
   }
 
-  logout() {
+  async logout() {
     try {
-      this.api.token = '' as any;
-      localStorage.removeItem('cartesia.token');
-      this.api.authState.set(false);
+      await this.api.logout();
       this.username = '';
       this.avatarInitial = 'U';
       this.accountOpen.set(false);